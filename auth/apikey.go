@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+// KeyLookup returns the principal of an API-key, or an error wrapping ErrInvalidCredentials if the key is unknown
+type KeyLookup func(ctx context.Context, key string) (*Principal, error)
+
+// APIKey authenticates requests by a key in a header
+type APIKey struct {
+	// Header with the key (default "X-API-Key")
+	Header string
+
+	// Lookup finds the principal of the key, see StaticKeys
+	Lookup KeyLookup
+}
+
+func (a *APIKey) header() string {
+	if a.Header == "" {
+		return "X-API-Key"
+	}
+	return a.Header
+}
+
+// Authenticate looks up the key of the request
+func (a *APIKey) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(a.header())
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+	if a.Lookup == nil {
+		return nil, fmt.Errorf("%w: no key-lookup", ErrInvalidCredentials)
+	}
+	p, err := a.Lookup(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("%w: no principal for the api-key", ErrInvalidCredentials)
+	}
+	if p.Method == "" {
+		p.Method = "apikey"
+	}
+	return p, nil
+}
+
+// Challenge names the header of the key
+func (a *APIKey) Challenge() string {
+	return fmt.Sprintf("APIKey header=%q", a.header())
+}
+
+// StaticKeys is a KeyLookup of a fixed set of keys, each with its principal
+func StaticKeys(keys map[string]Principal) KeyLookup {
+	return func(_ context.Context, key string) (*Principal, error) {
+		var found *Principal
+		for k, p := range keys {
+			// compare all keys, in constant time, to not reveal anything by the timing
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				p := p
+				found = &p
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("%w: unknown api-key", ErrInvalidCredentials)
+		}
+		return found, nil
+	}
+}
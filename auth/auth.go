@@ -0,0 +1,94 @@
+// Package auth authenticates requests, for use with Route.Auth or router.WithAuth.
+//
+// The authenticated Principal is stored in the context of the request, and can be used
+// as a handler-argument (auth.Principal or *auth.Principal).
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Errors returned by the authenticators
+var (
+	// ErrNoCredentials is when the request has no credentials for the authenticator
+	ErrNoCredentials = errors.New("no credentials")
+
+	// ErrInvalidCredentials is when the credentials are wrong, expired or otherwise not accepted
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string                 // the user, client or key-name
+	Method  string                 // how the principal was authenticated: "apikey", "basic" or "bearer"
+	Roles   []string               // roles of the principal, if any
+	Scopes  []string               // scopes of the principal (ex from the "scope"-claim of a token)
+	Claims  map[string]interface{} // all claims of a token, nil for other methods
+}
+
+// HasRole checks if the principal has the role
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// HasScope checks if the principal has the scope
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator finds and verifies the credentials of a request
+type Authenticator interface {
+	// Authenticate returns the principal of the request, ErrNoCredentials when the request has no
+	// credentials of this kind, or an error wrapping ErrInvalidCredentials when they are not accepted
+	Authenticate(r *http.Request) (*Principal, error)
+
+	// Challenge is the value of the 'WWW-Authenticate' header, when the request is rejected
+	Challenge() string
+}
+
+type anyOf []Authenticator
+
+// Any combines authenticators, using the first that finds credentials in the request
+func Any(authenticators ...Authenticator) Authenticator {
+	return anyOf(authenticators)
+}
+
+// Authenticate tries each authenticator in order
+func (list anyOf) Authenticate(r *http.Request) (*Principal, error) {
+	for _, a := range list {
+		p, err := a.Authenticate(r)
+		if !errors.Is(err, ErrNoCredentials) {
+			return p, err
+		}
+	}
+	return nil, ErrNoCredentials
+}
+
+// Challenge lists the challenges of all authenticators
+func (list anyOf) Challenge() string {
+	challenges := make([]string, 0, len(list))
+	for _, a := range list {
+		if c := a.Challenge(); c != "" {
+			challenges = append(challenges, c)
+		}
+	}
+	return strings.Join(challenges, ", ")
+}
+
+// Handle the principal...
+type principalKey struct{}
+
+// PrincipalFromCtx returns the authenticated principal associated to the context if any.
+func PrincipalFromCtx(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// CtxWithPrincipal adds the principal to the context
+func CtxWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
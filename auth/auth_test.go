@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var (
+	b64       = base64.RawURLEncoding
+	hmacKey   = []byte("0123456789abcdef0123456789abcdef")
+	rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+)
+
+func testJWKS(t *testing.T) *JWKS {
+	t.Helper()
+	set := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hs", "k": b64.EncodeToString(hmacKey)},
+		{"kty": "RSA", "kid": "rs", "alg": "RS256", "use": "sig",
+			"n": b64.EncodeToString(rsaKey.N.Bytes()), "e": b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "es", "crv": "P-256",
+			"x": b64.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))), "y": b64.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "RSA", "kid": "enc", "use": "enc"},
+	}}
+	data, _ := json.Marshal(set)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatalf("LoadJWKS: %v", err)
+	}
+	return jwks
+}
+
+// sign creates a token, signed with the test-key of the algorithm
+func sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, hmacKey)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "HS512":
+		mac := hmac.New(sha512.New, hmacKey)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		if err == nil {
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64.EncodeToString(sig)
+}
+
+func TestBearer(t *testing.T) {
+	jwks := testJWKS(t)
+	a := &Bearer{Keys: jwks, Issuer: "https://issuer", Audience: "api", Leeway: time.Minute}
+
+	exp := float64(time.Now().Add(time.Hour).Unix())
+	valid := map[string]interface{}{
+		"sub": "alice", "iss": "https://issuer", "aud": []string{"api", "other"}, "exp": exp,
+		"roles": []string{"admin"}, "scope": "read write",
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := make(map[string]interface{})
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[key] = value
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"HS256", sign(t, "HS256", "hs", valid), nil},
+		{"RS256", sign(t, "RS256", "rs", valid), nil},
+		{"ES256", sign(t, "ES256", "es", valid), nil},
+		{"without kid", sign(t, "ES256", "", valid), nil},
+		{"wrong kid", sign(t, "RS256", "es", valid), ErrInvalidCredentials},
+		{"HS512 with a short key", sign(t, "HS512", "hs", valid), ErrInvalidCredentials},
+		{"expired", sign(t, "HS256", "hs", with("exp", float64(time.Now().Add(-time.Hour).Unix()))), ErrInvalidCredentials},
+		{"within leeway", sign(t, "HS256", "hs", with("exp", float64(time.Now().Add(-time.Second).Unix()))), nil},
+		{"not yet valid", sign(t, "HS256", "hs", with("nbf", float64(time.Now().Add(time.Hour).Unix()))), ErrInvalidCredentials},
+		{"wrong issuer", sign(t, "HS256", "hs", with("iss", "other")), ErrInvalidCredentials},
+		{"wrong audience", sign(t, "HS256", "hs", with("aud", "other")), ErrInvalidCredentials},
+		{"alg none", b64.EncodeToString([]byte(`{"alg":"none"}`)) + "." + b64.EncodeToString([]byte(`{"sub":"x"}`)) + ".", ErrInvalidCredentials},
+		{"tampered", sign(t, "HS256", "hs", valid) + "x", ErrInvalidCredentials},
+		{"malformed", "abc", ErrInvalidCredentials},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			p, err := a.Authenticate(req)
+			if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if err == nil && (p.Subject != "alice" || p.Method != "bearer" || !p.HasRole("admin") || !p.HasScope("write")) {
+				t.Errorf("principal = %+v", p)
+			}
+		})
+	}
+
+	if _, err := a.Authenticate(httptest.NewRequest("GET", "/", nil)); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("without token: err = %v, want %v", err, ErrNoCredentials)
+	}
+}
+
+func TestParseJWKSInvalid(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"keys":[]}`,
+		`{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+		`{"keys":[{"kty":"unknown"}]}`,
+		`{"keys":[{"kty":"oct","k":""}]}`,
+		`{"keys":[{"kty":"oct","k":"AQ"}]}`,
+		`{"keys":[{"kty":"oct","alg":"HS512","k":"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"}]}`,
+	} {
+		if _, err := ParseJWKS([]byte(data)); !errors.Is(err, ErrInvalidJWKS) {
+			t.Errorf("ParseJWKS(%s): err = %v, want %v", data, err, ErrInvalidJWKS)
+		}
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	a := &APIKey{Lookup: StaticKeys(map[string]Principal{"secret": {Subject: "svc", Roles: []string{"reader"}}})}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("without key: err = %v", err)
+	}
+	req.Header.Set("X-API-Key", "wrong")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong key: err = %v", err)
+	}
+	req.Header.Set("X-API-Key", "secret")
+	if p, err := a.Authenticate(req); err != nil || p.Subject != "svc" || p.Method != "apikey" || !p.HasRole("reader") {
+		t.Errorf("got %+v, %v", p, err)
+	}
+	if a.Challenge() != `APIKey header="X-API-Key"` {
+		t.Errorf("challenge = %q", a.Challenge())
+	}
+}
+
+func TestNilPrincipal(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-API-Key", "key")
+	req.SetBasicAuth("bob", "pw")
+
+	keys := &APIKey{Lookup: func(context.Context, string) (*Principal, error) { return nil, nil }}
+	if p, err := keys.Authenticate(req); p != nil || !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("APIKey: got %+v, %v", p, err)
+	}
+	basic := &Basic{Check: func(context.Context, string, string) (*Principal, error) { return nil, nil }}
+	if p, err := basic.Authenticate(req); p != nil || !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Basic: got %+v, %v", p, err)
+	}
+}
+
+func TestBasic(t *testing.T) {
+	a := &Basic{Realm: "api", Check: StaticUsers(map[string]string{"bob": "pw"}, map[string][]string{"bob": {"admin"}})}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("without credentials: err = %v", err)
+	}
+	req.SetBasicAuth("bob", "wrong")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password: err = %v", err)
+	}
+	req.SetBasicAuth("eve", "")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("unknown user: err = %v", err)
+	}
+	req.SetBasicAuth("bob", "pw")
+	if p, err := a.Authenticate(req); err != nil || p.Subject != "bob" || p.Method != "basic" || !p.HasRole("admin") {
+		t.Errorf("got %+v, %v", p, err)
+	}
+}
+
+func TestAny(t *testing.T) {
+	a := Any(
+		&APIKey{Lookup: StaticKeys(map[string]Principal{"secret": {Subject: "svc"}})},
+		&Basic{Check: StaticUsers(map[string]string{"bob": "pw"}, nil)},
+	)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("bob", "pw")
+	if p, err := a.Authenticate(req); err != nil || p.Subject != "bob" {
+		t.Errorf("got %+v, %v", p, err)
+	}
+	if _, err := a.Authenticate(httptest.NewRequest("GET", "/", nil)); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("without credentials: err = %v", err)
+	}
+	if c := a.Challenge(); c != `APIKey header="X-API-Key", Basic realm="restricted", charset="UTF-8"` {
+		t.Errorf("challenge = %q", c)
+	}
+}
+
+func TestPrincipalFromCtx(t *testing.T) {
+	if _, ok := PrincipalFromCtx(context.Background()); ok {
+		t.Error("found a principal in an empty context")
+	}
+	ctx := CtxWithPrincipal(context.Background(), &Principal{Subject: "alice"})
+	if p, ok := PrincipalFromCtx(ctx); !ok || p.Subject != "alice" {
+		t.Errorf("got %+v, %t", p, ok)
+	}
+}
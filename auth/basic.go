@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+// PasswordCheck returns the principal of a user, or an error wrapping ErrInvalidCredentials if the password is wrong
+type PasswordCheck func(ctx context.Context, user, password string) (*Principal, error)
+
+// Basic authenticates requests using HTTP Basic authentication
+type Basic struct {
+	// Realm in the challenge (default "restricted")
+	Realm string
+
+	// Check verifies the user and password, see StaticUsers
+	Check PasswordCheck
+}
+
+// Authenticate checks the user and password of the request
+func (a *Basic) Authenticate(r *http.Request) (*Principal, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	if a.Check == nil {
+		return nil, fmt.Errorf("%w: no password-check", ErrInvalidCredentials)
+	}
+	p, err := a.Check(r.Context(), user, password)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("%w: no principal for the user", ErrInvalidCredentials)
+	}
+	if p.Subject == "" {
+		p.Subject = user
+	}
+	if p.Method == "" {
+		p.Method = "basic"
+	}
+	return p, nil
+}
+
+// Challenge asks for Basic authentication in the realm
+func (a *Basic) Challenge() string {
+	realm := a.Realm
+	if realm == "" {
+		realm = "restricted"
+	}
+	return fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm)
+}
+
+// StaticUsers is a PasswordCheck of a fixed set of users and passwords, each with
+// a principal of the user-name and the roles (if any)
+func StaticUsers(passwords map[string]string, roles map[string][]string) PasswordCheck {
+	return func(_ context.Context, user, password string) (*Principal, error) {
+		want, found := passwords[user]
+		// compare hashes, so the time doesn't depend on the length or the user being known
+		a, b := sha256.Sum256([]byte(want)), sha256.Sum256([]byte(password))
+		if subtle.ConstantTimeCompare(a[:], b[:]) != 1 || !found {
+			return nil, fmt.Errorf("%w: wrong user or password", ErrInvalidCredentials)
+		}
+		return &Principal{Subject: user, Roles: roles[user]}, nil
+	}
+}
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ninlil/butler/clock"
+)
+
+// now is the clock used for the expiry of tokens
+var now = clock.Now
+
+// Bearer authenticates requests by a JSON Web Token (JWT) in the 'Authorization: Bearer' header,
+// signed with HS256/384/512, RS256/384/512 or ES256/384/512 by a key in the JWKS
+type Bearer struct {
+	// Keys verifies the signature of the tokens, see LoadJWKS
+	Keys *JWKS
+
+	// Issuer is the required "iss"-claim, if set
+	Issuer string
+
+	// Audience is a required value in the "aud"-claim, if set
+	Audience string
+
+	// Leeway is the allowed clock-skew when checking "exp" and "nbf"
+	Leeway time.Duration
+
+	// RolesClaim is the claim with the roles of the principal (default "roles")
+	RolesClaim string
+
+	// Realm in the challenge, if set
+	Realm string
+}
+
+// Authenticate verifies the token of the request
+func (a *Bearer) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+	claims, err := a.verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	p := &Principal{Method: "bearer", Claims: claims}
+	p.Subject, _ = claims["sub"].(string)
+	rolesClaim := a.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+	p.Roles = stringList(claims[rolesClaim])
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = stringList(claims["scp"])
+	}
+	return p, nil
+}
+
+// Challenge asks for a bearer token
+func (a *Bearer) Challenge() string {
+	if a.Realm != "" {
+		return fmt.Sprintf("Bearer realm=%q", a.Realm)
+	}
+	return "Bearer"
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verify checks the signature and the claims of the token, returning the claims
+func (a *Bearer) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+	if a.Keys == nil {
+		return nil, fmt.Errorf("no keys")
+	}
+	if err := a.Keys.verify(header, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("claims: %w", err)
+	}
+	return claims, a.checkClaims(claims)
+}
+
+func (a *Bearer) checkClaims(claims map[string]interface{}) error {
+	t := now()
+	if exp, ok := claims["exp"].(float64); ok && t.After(unixTime(exp).Add(a.Leeway)) {
+		return fmt.Errorf("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && t.Before(unixTime(nbf).Add(-a.Leeway)) {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if a.Issuer != "" && claims["iss"] != a.Issuer {
+		return fmt.Errorf("wrong issuer")
+	}
+	if a.Audience != "" {
+		aud := stringList(claims["aud"])
+		if s, ok := claims["aud"].(string); ok {
+			aud = []string{s}
+		}
+		var found bool
+		for _, value := range aud {
+			found = found || value == a.Audience
+		}
+		if !found {
+			return fmt.Errorf("wrong audience")
+		}
+	}
+	return nil
+}
+
+// verify checks the signature with the keys matching the header
+func (jwks *JWKS) verify(header jwtHeader, signed, sig []byte) error {
+	var kty string
+	var hash crypto.Hash
+	switch header.Alg {
+	case "HS256", "RS256", "ES256":
+		hash = crypto.SHA256
+	case "HS384", "RS384", "ES384":
+		hash = crypto.SHA384
+	case "HS512", "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	switch header.Alg[:2] {
+	case "HS":
+		kty = "oct"
+	case "RS":
+		kty = "RSA"
+	case "ES":
+		kty = "EC"
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	for _, k := range jwks.candidates(header.Kid, header.Alg, kty) {
+		switch key := k.key.(type) {
+		case []byte:
+			if len(key) < hash.Size() {
+				continue // ex a key without an algorithm, too short for HS384/512
+			}
+			mac := hmac.New(hash.New, key)
+			mac.Write(signed)
+			if hmac.Equal(mac.Sum(nil), sig) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(key, hash, digest, sig) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			size := (key.Curve.Params().BitSize + 7) / 8
+			if len(sig) == 2*size && ecdsa.Verify(key, digest,
+				new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("invalid signature")
+}
+
+func decodeSegment(segment string, dest interface{}) error {
+	buf, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, dest)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// stringList converts a claim with an array of strings
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	var result []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// ErrInvalidJWKS is when a JSON Web Key Set can't be used
+var ErrInvalidJWKS = errors.New("invalid jwks")
+
+// JWKS is a JSON Web Key Set, with the keys used to verify tokens
+type JWKS struct {
+	keys []jwk
+}
+
+type jwk struct {
+	kid string
+	alg string
+	kty string
+	key crypto.PublicKey // *rsa.PublicKey, *ecdsa.PublicKey or []byte (for "oct")
+}
+
+type jwkJSON struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// LoadJWKS reads a JSON Web Key Set from a file
+func LoadJWKS(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// ParseJWKS parses a JSON Web Key Set, with RSA-, EC- (P-256, P-384, P-521) and symmetric (oct) keys.
+// Keys for other uses than signatures are skipped.
+func ParseJWKS(data []byte) (*JWKS, error) {
+	var set struct {
+		Keys []jwkJSON `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWKS, err)
+	}
+
+	jwks := new(JWKS)
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %d (%s): %v", ErrInvalidJWKS, i, k.Kid, err)
+		}
+		jwks.keys = append(jwks.keys, jwk{kid: k.Kid, alg: k.Alg, kty: k.Kty, key: key})
+	}
+	if len(jwks.keys) == 0 {
+		return nil, fmt.Errorf("%w: no signing keys", ErrInvalidJWKS)
+	}
+	return jwks, nil
+}
+
+func (k *jwkJSON) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err1 := decodeBigInt(k.N)
+		e, err2 := decodeBigInt(k.E)
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		var check ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, check = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, check = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, check = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err1 := decodeBigInt(k.X)
+		y, err2 := decodeBigInt(k.Y)
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, err
+		}
+		// validate the point, using its uncompressed form
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x.Bytes()) > size || len(y.Bytes()) > size {
+			return nil, errors.New("point is not on the curve")
+		}
+		point := append([]byte{4}, x.FillBytes(make([]byte, size))...)
+		point = append(point, y.FillBytes(make([]byte, size))...)
+		if _, err := check.NewPublicKey(point); err != nil {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "oct":
+		key, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, err
+		}
+		// at least the size of the hash, HS256 for keys without an algorithm
+		minSize := 32
+		switch k.Alg {
+		case "HS384":
+			minSize = 48
+		case "HS512":
+			minSize = 64
+		}
+		if len(key) < minSize {
+			return nil, fmt.Errorf("symmetric key shorter than %d bytes", minSize)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key-type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing key-parameter")
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
+
+// candidates returns the keys that may have signed a token
+func (jwks *JWKS) candidates(kid, alg, kty string) []jwk {
+	var list []jwk
+	for _, k := range jwks.keys {
+		if k.kty != kty || (k.alg != "" && k.alg != alg) || (kid != "" && k.kid != kid) {
+			continue
+		}
+		list = append(list, k)
+	}
+	return list
+}
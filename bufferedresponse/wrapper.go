@@ -65,6 +65,11 @@ func (rw *ResponseWriter) Reset() {
 	rw.buffer.Reset()
 }
 
+// Bytes returns the buffered content, valid until the next write or Reset
+func (rw *ResponseWriter) Bytes() []byte {
+	return rw.buffer.Bytes()
+}
+
 // Size returns the content size in bytes
 func (rw *ResponseWriter) Size() int {
 	return rw.buffer.Len()
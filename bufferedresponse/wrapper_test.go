@@ -139,3 +139,11 @@ func TestGet(t *testing.T) {
 		}
 	})
 }
+
+func TestBytes(t *testing.T) {
+	rw := Wrap(httptest.NewRecorder())
+	_, _ = rw.Write([]byte("hello"))
+	if string(rw.Bytes()) != "hello" {
+		t.Errorf("expected Bytes %q, got %q", "hello", rw.Bytes())
+	}
+}
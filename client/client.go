@@ -0,0 +1,293 @@
+// Package client makes outbound HTTP calls from butler services, propagating the request-id,
+// correlation-id and trace-context of the incoming request and using the same codecs as the router
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ninlil/butler/router"
+)
+
+// Client calls a service using a Transport
+type Client struct {
+	baseURL    string
+	timeout    time.Duration
+	accept     string
+	headers    http.Header
+	transport  *Transport
+	httpClient *http.Client
+}
+
+// Option is for 'functional options' to New
+type Option func(*Client)
+
+// WithBaseURL sets the url prepended to relative paths given to Call
+func WithBaseURL(base string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(base, "/")
+	}
+}
+
+// WithTimeout sets the default timeout of each call, including retries (default 30s)
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRetry enables retries of failed calls
+func WithRetry(retry Retry) Option {
+	return func(c *Client) {
+		c.transport.Retry = retry
+	}
+}
+
+// WithMetrics sets the receiver of call-metrics
+func WithMetrics(m Metrics) Option {
+	return func(c *Client) {
+		c.transport.Metrics = m
+	}
+}
+
+// WithBase sets the RoundTripper making the actual calls (default http.DefaultTransport)
+func WithBase(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport.Base = rt
+	}
+}
+
+// WithIDHeaders sets the names of the request-id and correlation-id headers, empty names are ignored
+func WithIDHeaders(request, correlation string) Option {
+	return func(c *Client) {
+		if request != "" {
+			c.transport.RequestIDHeader = request
+		}
+		if correlation != "" {
+			c.transport.CorrelationIDHeader = correlation
+		}
+	}
+}
+
+// WithHeader adds a header to every call (ex: for authentication)
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithAccept sets the "Accept" header, and the format of request-bodies (default "application/json")
+func WithAccept(mediaType string) Option {
+	return func(c *Client) {
+		c.accept = mediaType
+	}
+}
+
+// New creates a Client
+func New(opts ...Option) *Client {
+	c := &Client{
+		timeout:   30 * time.Second,
+		accept:    "application/json",
+		headers:   make(http.Header),
+		transport: &Transport{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.httpClient = &http.Client{Transport: c.transport}
+	return c
+}
+
+// HTTPClient returns an http.Client using the Transport of the client, for use with other libraries.
+// The timeout must be handled by the context of each request.
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// Do sends the request using the Transport of the client
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(req)
+}
+
+// CallOption is for 'functional options' to Call
+type CallOption func(*call)
+
+type call struct {
+	timeout time.Duration
+	query   url.Values
+	headers http.Header
+	cookies []*http.Cookie
+	form    url.Values
+	status  *int
+}
+
+// Timeout overrides the timeout of the client for this call
+func Timeout(d time.Duration) CallOption {
+	return func(c *call) {
+		c.timeout = d
+	}
+}
+
+// Query adds query-parameters to the url
+func Query(values url.Values) CallOption {
+	return func(c *call) {
+		if c.query == nil {
+			c.query = make(url.Values)
+		}
+		for key, list := range values {
+			c.query[key] = append(c.query[key], list...)
+		}
+	}
+}
+
+// Header adds a header to the call
+func Header(key, value string) CallOption {
+	return func(c *call) {
+		c.headers.Add(key, value)
+	}
+}
+
+// Cookie adds a cookie to the call
+func Cookie(name, value string) CallOption {
+	return func(c *call) {
+		c.cookies = append(c.cookies, &http.Cookie{Name: name, Value: value})
+	}
+}
+
+// Form sends the values as an "application/x-www-form-urlencoded" body, when Call has no 'in'
+func Form(values url.Values) CallOption {
+	return func(c *call) {
+		c.form = values
+	}
+}
+
+// Status stores the status-code of the response
+func Status(status *int) CallOption {
+	return func(c *call) {
+		c.status = status
+	}
+}
+
+// Call sends a request with 'in' encoded as body (unless nil) and decodes the response into 'out'
+// (unless nil). A response-status outside 2xx returns an *Error.
+func (c *Client) Call(ctx context.Context, method, path string, in, out interface{}, opts ...CallOption) error {
+	cl := call{timeout: c.timeout, headers: make(http.Header)}
+	for _, opt := range opts {
+		opt(&cl)
+	}
+
+	if cl.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cl.timeout)
+		defer cancel()
+	}
+
+	target := path
+	if c.baseURL != "" && !strings.Contains(path, "://") {
+		target = c.baseURL + "/" + strings.TrimPrefix(path, "/")
+	}
+	if len(cl.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + cl.query.Encode()
+	}
+
+	var body io.Reader
+	var contentType string
+	switch {
+	case in != nil:
+		buf, ct, err := c.encode(in)
+		if err != nil {
+			return fmt.Errorf("client: unable to encode body: %w", err)
+		}
+		body, contentType = bytes.NewReader(buf), ct
+	case cl.form != nil:
+		body, contentType = strings.NewReader(cl.form.Encode()), "application/x-www-form-urlencoded"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	for key, values := range c.headers {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	for key, values := range cl.headers {
+		req.Header[key] = values
+	}
+	for _, cookie := range cl.cookies {
+		req.AddCookie(cookie)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.accept != "" && req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", c.accept)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if cl.status != nil {
+		*cl.status = resp.StatusCode
+	}
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("client: unable to read response: %w", err)
+	}
+
+	ct := resp.Header.Get("Content-Type")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(resp.StatusCode, ct, buf)
+	}
+
+	if out != nil && len(buf) > 0 {
+		if err := router.Unmarshal(ct, buf, out); err != nil {
+			return fmt.Errorf("client: unable to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// encode the body in the format of the client, except strings and bytes which the router reads as-is
+func (c *Client) encode(in interface{}) ([]byte, string, error) {
+	switch v := in.(type) {
+	case []byte:
+		return v, "application/octet-stream", nil
+	case string, []string:
+		return router.Marshal("text/plain", in)
+	}
+	return router.Marshal(c.accept, in)
+}
+
+// Get is a shorthand for Call(ctx, "GET", path, nil, out, opts...)
+func (c *Client) Get(ctx context.Context, path string, out interface{}, opts ...CallOption) error {
+	return c.Call(ctx, http.MethodGet, path, nil, out, opts...)
+}
+
+// Post is a shorthand for Call(ctx, "POST", path, in, out, opts...)
+func (c *Client) Post(ctx context.Context, path string, in, out interface{}, opts ...CallOption) error {
+	return c.Call(ctx, http.MethodPost, path, in, out, opts...)
+}
+
+// Put is a shorthand for Call(ctx, "PUT", path, in, out, opts...)
+func (c *Client) Put(ctx context.Context, path string, in, out interface{}, opts ...CallOption) error {
+	return c.Call(ctx, http.MethodPut, path, in, out, opts...)
+}
+
+// Delete is a shorthand for Call(ctx, "DELETE", path, nil, out, opts...)
+func (c *Client) Delete(ctx context.Context, path string, out interface{}, opts ...CallOption) error {
+	return c.Call(ctx, http.MethodDelete, path, nil, out, opts...)
+}
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ninlil/butler/router"
+	"github.com/ninlil/butler/tracing"
+)
+
+type item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestPropagation(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	tc := router.TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Flags:   router.TraceFlagSampled,
+		State:   "vendor=value",
+	}
+	ctx := router.CtxWithReqID(context.Background(), "req-1")
+	ctx = router.CtxWithCorrID(ctx, "corr-1")
+	ctx = router.CtxWithTrace(ctx, tc)
+
+	c := New(WithBaseURL(srv.URL), WithIDHeaders("X-Trace-Request", ""))
+	if err := c.Get(ctx, "/", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"X-Trace-Request":  "req-1",
+		"X-Correlation-Id": "corr-1",
+		"traceparent":      tc.TraceParent(),
+		"tracestate":       "vendor=value",
+		"Accept":           "application/json",
+	}
+	for key, want := range tests {
+		if v := got.Get(key); v != want {
+			t.Errorf("header %s = %q, want %q", key, v, want)
+		}
+	}
+}
+
+func TestClientSpan(t *testing.T) {
+	exp := tracing.NewMemoryExporter()
+	tracing.Enable(exp)
+	t.Cleanup(tracing.Disable)
+
+	var parent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	ctx, server := tracing.Start(context.Background(), "server")
+	if err := New().Get(ctx, srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	server.End()
+	tracing.Flush()
+
+	span := exp.Find("HTTP GET")
+	if span == nil {
+		t.Fatalf("no client span, got %+v", exp.Spans())
+	}
+	if span.Kind != tracing.KindClient || span.ParentID != server.SpanID() {
+		t.Errorf("kind/parent = %d/%s, want %d/%s", span.Kind, span.ParentID, tracing.KindClient, server.SpanID())
+	}
+	tc, err := router.ParseTraceParent(parent)
+	if err != nil || tc.TraceID != span.TraceID || tc.ParentID != span.SpanID {
+		t.Errorf("traceparent = %q, want the client span as parent", parent)
+	}
+}
+
+func TestCall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in item
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		in.ID = 42
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(in)
+	}))
+	defer srv.Close()
+
+	var out item
+	var status int
+	err := New(WithBaseURL(srv.URL+"/")).Post(context.Background(), "/items", &item{Name: "apple"}, &out, Status(&status))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusCreated || out.ID != 42 || out.Name != "apple" {
+		t.Errorf("got %d %+v", status, out)
+	}
+}
+
+func TestCallError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`<result><error>not found</error></result>`))
+	}))
+	defer srv.Close()
+
+	err := New(WithAccept("application/xml")).Get(context.Background(), srv.URL, nil)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	if e.Status != http.StatusNotFound || e.Message != "not found" {
+		t.Errorf("got %d %q", e.Status, e.Message)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var info CallInfo
+	c := New(
+		WithRetry(Retry{Max: 3, Backoff: time.Millisecond}),
+		WithMetrics(MetricsFunc(func(ci CallInfo) { info = ci })),
+	)
+
+	var out string
+	if err := c.Put(context.Background(), srv.URL, "body", &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != "ok" || calls.Load() != 3 {
+		t.Errorf("got %q after %d calls", out, calls.Load())
+	}
+	if info.Attempts != 3 || info.Status != http.StatusOK || info.Method != http.MethodPut {
+		t.Errorf("metrics = %+v", info)
+	}
+}
+
+func TestNoRetryPost(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := New(WithRetry(Retry{Max: 3, Backoff: time.Millisecond}))
+	err := c.Post(context.Background(), srv.URL, "body", nil)
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusServiceUnavailable {
+		t.Errorf("err = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("got %d calls, want 1", calls.Load())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	err := New().Get(context.Background(), srv.URL, nil, Timeout(20*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("call took %v", d)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	tr := &Transport{Retry: Retry{Backoff: 100 * time.Millisecond, MaxDelay: time.Second}}
+
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second} {
+		if d := tr.delay(attempt, nil); d < want/2 || d > want {
+			t.Errorf("delay(%d) = %v, want %v..%v", attempt, d, want/2, want)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"30"}}}
+	if d := tr.delay(1, resp); d != time.Second {
+		t.Errorf("delay with Retry-After = %v, want %v", d, time.Second)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RawQuery
+	}))
+	defer srv.Close()
+
+	err := New(WithBaseURL(srv.URL)).Get(context.Background(), "/search?a=1", nil, Query(map[string][]string{"q": {"x y"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "a=1&q=x+y") {
+		t.Errorf("query = %q", got)
+	}
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ninlil/butler/router"
+)
+
+// Error is returned by Call when the response-status is outside 2xx
+type Error struct {
+	Status  int
+	Message string // the "error" of a butler error-response, if any
+	Body    []byte
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("client: %d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+	}
+	return fmt.Sprintf("client: %d %s", e.Status, http.StatusText(e.Status))
+}
+
+// errorBody is the error-format written by the router
+type errorBody struct {
+	JSON interface{} `json:"error" xml:"-"`
+	XML  string      `json:"-" xml:"error"`
+}
+
+func newError(status int, contentType string, body []byte) *Error {
+	e := &Error{Status: status, Body: body}
+	var eb errorBody
+	if len(body) > 0 && router.Unmarshal(contentType, body, &eb) == nil {
+		switch {
+		case eb.JSON != nil:
+			e.Message = fmt.Sprint(eb.JSON)
+		case eb.XML != "":
+			e.Message = eb.XML
+		}
+	}
+	return e
+}
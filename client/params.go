@@ -0,0 +1,108 @@
+package client
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/ninlil/butler/router"
+)
+
+// Params collects the values of a call in the same way as the router reads them from the
+// `from`-tag of the arguments, used by clients generated with butler-gen
+type Params struct {
+	path    []string
+	query   url.Values
+	headers http.Header
+	cookies []*http.Cookie
+	form    url.Values
+	body    interface{}
+}
+
+// Add a value from a field, 'from' is the `from`-tag and 'name' is the `json`-tag of the field.
+// Nil-pointers are always skipped, and zero-values unless required.
+func (p *Params) Add(from, name string, value interface{}, required bool) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return
+	}
+	if v.Kind() == reflect.Ptr {
+		value = v.Elem().Interface()
+	} else if !required && v.IsZero() {
+		return
+	}
+
+	if from == "body" {
+		p.body = value
+		return
+	}
+
+	txt := FormatValue(value)
+	switch from {
+	case "query":
+		if p.query == nil {
+			p.query = make(url.Values)
+		}
+		p.query.Add(name, txt)
+	case "header":
+		if p.headers == nil {
+			p.headers = make(http.Header)
+		}
+		p.headers.Add(name, txt)
+	case "cookie":
+		p.cookies = append(p.cookies, &http.Cookie{Name: name, Value: txt})
+	case "form":
+		if p.form == nil {
+			p.form = make(url.Values)
+		}
+		p.form.Add(name, txt)
+	default:
+		p.path = append(p.path, name, txt)
+	}
+}
+
+// Path fills the {param}s of the route-path
+func (p *Params) Path(path string) (string, error) {
+	return router.ExpandPath(path, p.path...)
+}
+
+// Body returns the body-value, or nil if none
+func (p *Params) Body() interface{} {
+	return p.body
+}
+
+// Options returns the query, headers, cookies and form as call-options, followed by opts
+func (p *Params) Options(opts ...CallOption) []CallOption {
+	var list []CallOption
+	if p.query != nil {
+		list = append(list, Query(p.query))
+	}
+	for key, values := range p.headers {
+		for _, value := range values {
+			list = append(list, Header(key, value))
+		}
+	}
+	for _, cookie := range p.cookies {
+		list = append(list, Cookie(cookie.Name, cookie.Value))
+	}
+	if p.form != nil {
+		list = append(list, Form(p.form))
+	}
+	return append(list, opts...)
+}
+
+// FormatValue formats a value the way the router parses it
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ninlil/butler/router"
+)
+
+type paramsArgs struct {
+	ID      int           `json:"id" from:"path"`
+	Limit   int           `json:"limit" from:"query" default:"10"`
+	Since   time.Duration `json:"since" from:"query"`
+	Token   string        `json:"X-Token" from:"header"`
+	Session string        `json:"session" from:"cookie"`
+	Body    *item         `from:"body"`
+}
+
+type paramsResult struct {
+	ID      int    `json:"id"`
+	Limit   int    `json:"limit"`
+	Since   string `json:"since"`
+	Token   string `json:"token"`
+	Session string `json:"session"`
+	Name    string `json:"name"`
+}
+
+func paramsHandler(args *paramsArgs) *paramsResult {
+	res := &paramsResult{ID: args.ID, Limit: args.Limit, Since: args.Since.String(), Token: args.Token, Session: args.Session}
+	if args.Body != nil {
+		res.Name = args.Body.Name
+	}
+	return res
+}
+
+type formArgs struct {
+	Name string `json:"name" from:"form"`
+}
+
+func TestParams(t *testing.T) {
+	r, err := router.New([]router.Route{
+		{Name: "params", Method: "PUT", Path: "/items/{id}", Handler: paramsHandler},
+		{Name: "form", Method: "POST", Path: "/form", Handler: func(args *formArgs) string { return args.Name }},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	c := New(WithBaseURL(srv.URL))
+
+	limit := 0
+	var p Params
+	p.Add("path", "id", 0, true)
+	p.Add("query", "limit", &limit, false)
+	p.Add("query", "since", 90*time.Second, false)
+	p.Add("header", "X-Token", "secret", false)
+	p.Add("cookie", "session", "s1", false)
+	p.Add("cookie", "unused", "", false)
+	p.Add("body", "", &item{Name: "apple"}, false)
+
+	path, err := p.Path("/items/{id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got paramsResult
+	if err := c.Call(context.Background(), "PUT", path, p.Body(), &got, p.Options()...); err != nil {
+		t.Fatal(err)
+	}
+	want := paramsResult{ID: 0, Limit: 0, Since: "1m30s", Token: "secret", Session: "s1", Name: "apple"}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	var p2 Params
+	p2.Add("form", "name", "bob", false)
+	var name string
+	if err := c.Call(context.Background(), "POST", "/form", p2.Body(), &name, p2.Options()...); err != nil || name != "bob" {
+		t.Errorf("form: got %q, %v", name, err)
+	}
+}
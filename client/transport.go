@@ -0,0 +1,273 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ninlil/butler/clock"
+	"github.com/ninlil/butler/log"
+	"github.com/ninlil/butler/router"
+	"github.com/ninlil/butler/tracing"
+)
+
+// Transport is an http.RoundTripper that propagates the request-id, correlation-id and
+// trace-context of the request-context to the outbound request, logs the call using the
+// logger of the context, reports it to the Metrics (if any) and retries failed calls.
+type Transport struct {
+	// Base is the RoundTripper making the actual calls (default http.DefaultTransport)
+	Base http.RoundTripper
+
+	// RequestIDHeader and CorrelationIDHeader are the names of the propagated headers
+	// (default "X-Request-Id" and "X-Correlation-Id")
+	RequestIDHeader     string
+	CorrelationIDHeader string
+
+	// Retry configures retries of failed calls (default no retries)
+	Retry Retry
+
+	// Metrics is called after each call (optional)
+	Metrics Metrics
+}
+
+// Retry configures when and how often a failed call is retried.
+// Only calls with an idempotent method and a replayable body are retried, when the call
+// fails with an error or any of the status-codes.
+type Retry struct {
+	Max      int           // max number of retries, 0 disables retries
+	Backoff  time.Duration // delay before the first retry, doubled for each retry (default 100ms)
+	MaxDelay time.Duration // max delay between retries, including "Retry-After" (default 10s)
+	Statuses []int         // status-codes to retry (default 429, 502, 503 and 504)
+}
+
+var defaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// CallInfo describes a finished outbound call
+type CallInfo struct {
+	Method   string
+	Host     string
+	Path     string
+	Status   int // 0 if the call failed without a response
+	Attempts int
+	Duration time.Duration
+	Err      error
+}
+
+// Metrics receives information about each outbound call
+type Metrics interface {
+	ObserveCall(info CallInfo)
+}
+
+// MetricsFunc adapts a function to the Metrics interface
+type MetricsFunc func(info CallInfo)
+
+// ObserveCall calls f(info)
+func (f MetricsFunc) ObserveCall(info CallInfo) {
+	f(info)
+}
+
+// RoundTrip executes a single call, including any retries
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := clock.Now()
+
+	ctx, span := tracing.Start(ctx, "HTTP "+req.Method,
+		tracing.WithKind(tracing.KindClient),
+		tracing.WithAttr("http.request.method", req.Method),
+		tracing.WithAttr("server.address", req.URL.Host),
+		tracing.WithAttr("url.full", req.URL.String()))
+	defer span.End()
+
+	req = t.prepare(ctx, req)
+
+	var resp *http.Response
+	var err error
+	attempts := 0
+	for {
+		attempts++
+		resp, err = t.base().RoundTrip(req)
+		if attempts > t.Retry.Max || !t.retryable(req, resp, err) {
+			break
+		}
+
+		delay := t.delay(attempts, resp)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if req, err = rewind(req); err != nil {
+			resp = nil
+			break
+		}
+
+		log.FromCtx(ctx).Debug().Msgf("client: retrying %s %s in %v (attempt %d)", req.Method, req.URL.Redacted(), delay, attempts+1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			resp, err = nil, ctx.Err()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	info := CallInfo{
+		Method:   req.Method,
+		Host:     req.URL.Host,
+		Path:     req.URL.Path,
+		Attempts: attempts,
+		Duration: clock.Since(start),
+		Err:      err,
+	}
+	if resp != nil {
+		info.Status = resp.StatusCode
+		span.SetAttr("http.response.status_code", resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			span.SetStatus(tracing.StatusError, "")
+		}
+	}
+	span.RecordError(err)
+	t.report(ctx, req, info)
+
+	return resp, err
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// prepare clones the request (a RoundTripper must not modify the request) and adds the headers
+func (t *Transport) prepare(ctx context.Context, req *http.Request) *http.Request {
+	req = req.Clone(ctx)
+
+	reqHeader, corrHeader := t.RequestIDHeader, t.CorrelationIDHeader
+	if reqHeader == "" {
+		reqHeader = "X-Request-Id"
+	}
+	if corrHeader == "" {
+		corrHeader = "X-Correlation-Id"
+	}
+	if id := router.ReqIDFromCtx(ctx); id != "" && req.Header.Get(reqHeader) == "" {
+		req.Header.Set(reqHeader, id)
+	}
+	if id := router.CorrIDFromCtx(ctx); id != "" && req.Header.Get(corrHeader) == "" {
+		req.Header.Set(corrHeader, id)
+	}
+
+	if req.Header.Get("traceparent") == "" {
+		tc, ok := router.TraceFromCtx(ctx)
+		if span := tracing.FromCtx(ctx); span != nil {
+			// the client-span is the parent of the remote span
+			var flags byte
+			if span.Sampled() {
+				flags = router.TraceFlagSampled
+			}
+			tc = router.TraceContext{TraceID: span.TraceID(), SpanID: span.SpanID(), Flags: flags, State: tc.State}
+			ok = true
+		}
+		if ok {
+			req.Header.Set("traceparent", tc.TraceParent())
+			if tc.State != "" {
+				req.Header.Set("tracestate", tc.State)
+			}
+		}
+	}
+	return req
+}
+
+func (t *Transport) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+	statuses := t.Retry.Statuses
+	if statuses == nil {
+		statuses = defaultRetryStatuses
+	}
+	for _, status := range statuses {
+		if resp.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the time to wait before the next attempt, honoring a "Retry-After" header
+func (t *Transport) delay(attempt int, resp *http.Response) time.Duration {
+	maxDelay := t.Retry.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 10 * time.Second
+	}
+
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, maxDelay)
+		}
+	}
+
+	d := t.Retry.Backoff
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, maxDelay)
+
+	// jitter between d/2 and d, to avoid all clients retrying at the same time
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return req, fmt.Errorf("client: unable to rewind body: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
+
+func (t *Transport) report(ctx context.Context, req *http.Request, info CallInfo) {
+	logger := log.FromCtx(ctx)
+	ev := logger.Debug()
+	if info.Err != nil {
+		ev = logger.Warn().Err(info.Err)
+	}
+	ev.Str("method", info.Method).
+		Str("url", req.URL.Redacted()).
+		Int("status", info.Status).
+		Int("attempts", info.Attempts).
+		Dur("duration", info.Duration).
+		Msg("client: outbound call")
+
+	if t.Metrics != nil {
+		t.Metrics.ObserveCall(info)
+	}
+}
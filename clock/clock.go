@@ -0,0 +1,87 @@
+// Package clock is the source of time and randomness used by butler (the router, workers, auth and tracing),
+// which can be replaced by a fake clock in tests to get deterministic timestamps, durations and ids
+// (see NewFake and package clocktest).
+//
+// Timers and timeouts (ex the max wait of concurrency limits) still use real time.
+package clock
+
+import (
+	"crypto/rand"
+	"sync/atomic"
+	"time"
+)
+
+// Clock tells the time
+type Clock interface {
+	Now() time.Time
+}
+
+// Random is implemented by clocks that also provide the random bytes (of ids, nonces etc)
+type Random interface {
+	Read(buf []byte) (int, error)
+}
+
+type holder struct {
+	clock Clock
+}
+
+var current atomic.Pointer[holder]
+
+// Real is the system clock, with random bytes from crypto/rand
+type Real struct{}
+
+// Now returns the current local time
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Read fills the buffer with random bytes
+func (Real) Read(buf []byte) (int, error) {
+	return rand.Read(buf)
+}
+
+// Set replaces the clock, returning a function restoring the previous one
+func Set(c Clock) (restore func()) {
+	prev := current.Swap(&holder{clock: c})
+	return func() {
+		current.Store(prev)
+	}
+}
+
+// Get returns the current clock
+func Get() Clock {
+	if h := current.Load(); h != nil {
+		return h.clock
+	}
+	return Real{}
+}
+
+// IsFake is true when the current clock isn't the real one
+func IsFake() bool {
+	_, real := Get().(Real)
+	return !real
+}
+
+// Now returns the time of the current clock
+func Now() time.Time {
+	return Get().Now()
+}
+
+// Since returns the time elapsed since t, by the current clock
+func Since(t time.Time) time.Duration {
+	return Now().Sub(t)
+}
+
+// Read fills the buffer with random bytes, from the current clock if it implements Random
+// (or else crypto/rand), and panics if no random bytes are available
+func Read(buf []byte) {
+	var err error
+	if r, ok := Get().(Random); ok {
+		_, err = r.Read(buf)
+	} else {
+		_, err = rand.Read(buf)
+	}
+	if err != nil {
+		panic("clock: unable to read random bytes: " + err.Error())
+	}
+}
@@ -0,0 +1,79 @@
+package clock
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestFake(t *testing.T) {
+	if IsFake() {
+		t.Fatal("the real clock should be the default")
+	}
+
+	fake := NewFake(start)
+	t.Cleanup(Set(fake))
+	if !IsFake() || !Now().Equal(start) {
+		t.Fatalf("Now() = %v, want %v", Now(), start)
+	}
+
+	fake.Advance(time.Minute)
+	if got := Since(start); got != time.Minute {
+		t.Errorf("Since() = %v, want 1m", got)
+	}
+
+	fake.Set(start)
+	fake.AutoAdvance(time.Second)
+	a, b := Now(), Now()
+	if !a.Equal(start) || b.Sub(a) != time.Second {
+		t.Errorf("auto-advance: %v, %v", a, b)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	restore := Set(NewFake(start))
+	if !IsFake() {
+		t.Fatal("expected the fake clock")
+	}
+	restore()
+	if IsFake() {
+		t.Error("expected the real clock after restore")
+	}
+	if d := Since(time.Now()); d > time.Second || d < -time.Second {
+		t.Errorf("the real clock is off by %v", d)
+	}
+}
+
+func TestRead(t *testing.T) {
+	read := func() []byte {
+		t.Helper()
+		restore := Set(NewFake(start))
+		defer restore()
+		a, b := make([]byte, 16), make([]byte, 16)
+		Read(a)
+		Read(b)
+		if bytes.Equal(a, b) {
+			t.Errorf("the same bytes twice: %x", a)
+		}
+		return append(a, b...)
+	}
+	if first, second := read(), read(); !bytes.Equal(first, second) {
+		t.Errorf("fake clocks should give the same bytes: %x != %x", first, second)
+	}
+
+	a, b := make([]byte, 16), make([]byte, 16)
+	Read(a)
+	Read(b)
+	if bytes.Equal(a, b) {
+		t.Errorf("the same random bytes twice: %x", a)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	next := Sequence("req")
+	if a, b := next(), next(); a != "req-1" || b != "req-2" {
+		t.Errorf("got %q, %q", a, b)
+	}
+}
@@ -0,0 +1,42 @@
+// Package clocktest installs a fake clock for the duration of a test:
+//
+//	func TestGolden(t *testing.T) {
+//		fake := clocktest.UseFake(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+//		fake.AutoAdvance(time.Millisecond)
+//		...
+//	}
+//
+// The clock and the timestamps of the log are package-wide, so tests using UseFake must not run in parallel.
+package clocktest
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ninlil/butler/clock"
+	"github.com/rs/zerolog"
+)
+
+// inUse is set while a fake clock from UseFake is installed
+var inUse atomic.Bool
+
+// UseFake sets a new fake clock for the duration of the test, also used for the timestamps of the log.
+// The test fails at once if another fake clock from UseFake is in use, by a parallel test or earlier in the same test.
+func UseFake(t testing.TB, start time.Time) *clock.Fake {
+	t.Helper()
+	if !inUse.CompareAndSwap(false, true) {
+		t.Fatal("clocktest: a fake clock is already in use, UseFake can't be used by parallel tests or twice in a test")
+		return nil
+	}
+	fake := clock.NewFake(start)
+	restore := clock.Set(fake)
+	org := zerolog.TimestampFunc
+	zerolog.TimestampFunc = fake.Now
+	t.Cleanup(func() {
+		zerolog.TimestampFunc = org
+		restore()
+		inUse.Store(false)
+	})
+	return fake
+}
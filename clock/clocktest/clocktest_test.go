@@ -0,0 +1,56 @@
+package clocktest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ninlil/butler/clock"
+	"github.com/rs/zerolog"
+)
+
+func TestUseFake(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	t.Run("fake", func(t *testing.T) {
+		fake := UseFake(t, start)
+		fake.Advance(time.Second)
+		if !clock.IsFake() || !clock.Now().Equal(start.Add(time.Second)) {
+			t.Errorf("Now() = %v", clock.Now())
+		}
+
+		var buf bytes.Buffer
+		logger := zerolog.New(&buf).With().Timestamp().Logger()
+		logger.Info().Msg("x")
+		if want := start.Add(time.Second).Format(zerolog.TimeFieldFormat); !strings.Contains(buf.String(), want) {
+			t.Errorf("log = %q, want the time %s", buf.String(), want)
+		}
+	})
+	if clock.IsFake() || zerolog.TimestampFunc().Year() == start.Year() {
+		t.Error("the clocks weren't restored")
+	}
+}
+
+// fatalTB collects the fatal error of the test instead of stopping it
+type fatalTB struct {
+	testing.TB
+	fatal string
+}
+
+func (tb *fatalTB) Fatal(args ...interface{}) {
+	tb.fatal = fmt.Sprint(args...)
+}
+
+func TestUseFakeTwice(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake := UseFake(t, start)
+
+	tb := &fatalTB{TB: t}
+	if UseFake(tb, start.Add(time.Hour)) != nil || !strings.Contains(tb.fatal, "already in use") {
+		t.Errorf("second UseFake: fatal %q", tb.fatal)
+	}
+	if !clock.Now().Equal(start) || clock.Get() != fake {
+		t.Errorf("the first fake clock was replaced, Now() = %v", clock.Now())
+	}
+}
@@ -0,0 +1,68 @@
+package clock
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Fake is a clock controlled by the test, with deterministic random bytes
+type Fake struct {
+	mutex sync.Mutex
+	now   time.Time
+	step  time.Duration
+	rand  *rand.ChaCha8
+}
+
+// NewFake creates a fake clock starting at the time, with the random bytes from a fixed seed
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start, rand: rand.NewChaCha8([32]byte{})}
+}
+
+// Now returns the time of the clock, and advances it by the auto-advance (if any)
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := f.now
+	f.now = f.now.Add(f.step)
+	return now
+}
+
+// Set changes the time of the clock
+func (f *Fake) Set(t time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = t
+}
+
+// Advance moves the clock forward
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// AutoAdvance moves the clock forward by d after each call to Now, so durations aren't zero
+func (f *Fake) AutoAdvance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.step = d
+}
+
+// Read fills the buffer with the next deterministic random bytes
+func (f *Fake) Read(buf []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.rand.Read(buf)
+}
+
+// Sequence returns a generator of deterministic ids, "prefix-1", "prefix-2" and so on,
+// ex for router.WithIDGenerator
+func Sequence(prefix string) func() string {
+	var n atomic.Uint64
+	return func() string {
+		return fmt.Sprintf("%s-%d", prefix, n.Add(1))
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const clientPath = "github.com/ninlil/butler/client"
+
+type config struct {
+	dir      string
+	varName  string
+	output   string
+	pkgName  string
+	typeName string
+}
+
+// methods of client.Client, which can't be used as route-methods
+var clientMethods = map[string]bool{
+	"Call": true, "Do": true, "Get": true, "Post": true, "Put": true, "Delete": true, "HTTPClient": true,
+}
+
+type generator struct {
+	src     *source
+	cfg     *config
+	imports map[string]string // import-path -> name
+	types   map[string]string // local type -> name in the client
+	queue   []string          // local types to copy
+	names   map[string]bool   // top-level names in use
+	methods map[string]bool
+	err     error
+}
+
+func generate(cfg *config, stderr io.Writer) ([]byte, error) {
+	warn := func(format string, args ...interface{}) {
+		fmt.Fprintf(stderr, "butler-gen: "+format+"\n", args...)
+	}
+
+	src, err := loadSource(cfg.dir, cfg.output)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := src.routes(cfg.varName, warn)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		src:     src,
+		cfg:     cfg,
+		imports: map[string]string{"context": "context", clientPath: "client"},
+		types:   make(map[string]string),
+		names:   map[string]bool{cfg.typeName: true, "New": true},
+		methods: make(map[string]bool),
+	}
+
+	var methods bytes.Buffer
+	for i := range routes {
+		g.method(&methods, &routes[i], warn)
+	}
+
+	var types bytes.Buffer
+	for len(g.queue) > 0 {
+		name := g.queue[0]
+		g.queue = g.queue[1:]
+		g.copyType(&types, name)
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by butler-gen from %s.%s; DO NOT EDIT.\n\n", src.name, cfg.varName)
+	fmt.Fprintf(&out, "package %s\n\n", cfg.pkgName)
+	out.WriteString("import (\n")
+	for _, std := range []bool{true, false} {
+		for _, path := range sortedKeys(g.imports) {
+			if isStd(path) != std {
+				continue
+			}
+			name := g.imports[path]
+			if name == path[strings.LastIndex(path, "/")+1:] {
+				fmt.Fprintf(&out, "\t%q\n", path)
+			} else {
+				fmt.Fprintf(&out, "\t%s %q\n", name, path)
+			}
+		}
+		if std {
+			out.WriteString("\n")
+		}
+	}
+	out.WriteString(")\n\n")
+	fmt.Fprintf(&out, "// %s calls the routes of %s.%s\n", cfg.typeName, src.name, cfg.varName)
+	fmt.Fprintf(&out, "type %s struct {\n\t*client.Client\n}\n\n", cfg.typeName)
+	fmt.Fprintf(&out, "// New creates a %s, see client.New for the options\n", cfg.typeName)
+	fmt.Fprintf(&out, "func New(opts ...client.Option) *%s {\n\treturn &%s{Client: client.New(opts...)}\n}\n\n", cfg.typeName, cfg.typeName)
+	out.Write(methods.Bytes())
+	out.Write(types.Bytes())
+
+	src2, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("generated code is invalid: %w", err)
+	}
+	return src2, nil
+}
+
+func (g *generator) method(w io.Writer, rd *routeDef, warn func(string, ...interface{})) {
+	name := methodName(rd.name)
+	if clientMethods[name] || g.methods[name] {
+		g.fail(fmt.Errorf("route %q: method %s is already in use, rename the route", rd.name, name))
+		return
+	}
+	g.methods[name] = true
+
+	fields := g.src.fields(rd, warn)
+	var params string
+	if len(fields) > 0 {
+		params = name + "Params"
+		g.reserve(params)
+		fmt.Fprintf(w, "// %s are the parameters of %s\n", params, name)
+		fmt.Fprintf(w, "type %s struct {\n", params)
+		for _, f := range fields {
+			typ := g.typeString(f.typ, f.file)
+			if _, isPtr := f.typ.(*ast.StarExpr); f.optional && !isPtr {
+				typ = "*" + typ
+			}
+			if f.tag != "" {
+				fmt.Fprintf(w, "\t%s %s %s\n", f.name, typ, "`"+f.tag+"`")
+			} else {
+				fmt.Fprintf(w, "\t%s %s\n", f.name, typ)
+			}
+		}
+		fmt.Fprintf(w, "}\n\n")
+	}
+
+	method := strconv.Quote(rd.method)
+	args := "ctx context.Context"
+	if rd.method == "*" {
+		method = "method"
+		args += ", method string"
+	}
+	if params != "" {
+		args += ", params *" + params
+	}
+
+	var data, ret string
+	if res := result(rd.handler); res != nil {
+		data = g.typeString(res, rd.file)
+		ret = "out, "
+	}
+
+	fmt.Fprintf(w, "// %s calls the route %q (%s %s)\n", name, rd.name, rd.method, rd.path)
+	if data != "" {
+		fmt.Fprintf(w, "func (c *%s) %s(%s, opts ...client.CallOption) (%s, error) {\n", g.cfg.typeName, name, args, data)
+		fmt.Fprintf(w, "\tvar out %s\n", data)
+	} else {
+		fmt.Fprintf(w, "func (c *%s) %s(%s, opts ...client.CallOption) error {\n", g.cfg.typeName, name, args)
+	}
+	fmt.Fprintf(w, "\tvar p client.Params\n")
+	if params != "" {
+		fmt.Fprintf(w, "\tif params != nil {\n")
+		for _, f := range fields {
+			fmt.Fprintf(w, "\t\tp.Add(%q, %q, params.%s, %t)\n", f.from, f.json, f.name, f.required)
+		}
+		fmt.Fprintf(w, "\t}\n")
+	}
+	fmt.Fprintf(w, "\tpath, err := p.Path(%q)\n", rd.path)
+	fmt.Fprintf(w, "\tif err != nil {\n\t\treturn %serr\n\t}\n", ret)
+	if data != "" {
+		fmt.Fprintf(w, "\terr = c.Call(ctx, %s, path, p.Body(), &out, p.Options(opts...)...)\n", method)
+		fmt.Fprintf(w, "\treturn out, err\n")
+	} else {
+		fmt.Fprintf(w, "\treturn c.Call(ctx, %s, path, p.Body(), nil, p.Options(opts...)...)\n", method)
+	}
+	fmt.Fprintf(w, "}\n\n")
+}
+
+// copyType writes a copy of a local type, named as in the client
+func (g *generator) copyType(w io.Writer, name string) {
+	d := g.src.types[name]
+	spec := d.node.(*ast.TypeSpec)
+	if spec.TypeParams != nil {
+		g.fail(fmt.Errorf("type %s: generic types are not supported", name))
+		return
+	}
+	assign := " "
+	if spec.Assign.IsValid() {
+		assign = " = "
+	}
+	fmt.Fprintf(w, "// %s is a copy of %s.%s\n", g.types[name], g.src.name, name)
+	fmt.Fprintf(w, "type %s%s%s\n\n", g.types[name], assign, g.typeString(spec.Type, d.file))
+}
+
+func (g *generator) typeString(expr ast.Expr, file *ast.File) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if _, local := g.src.types[t.Name]; local {
+			return g.localType(t.Name)
+		}
+		return t.Name
+
+	case *ast.ParenExpr:
+		return g.typeString(t.X, file)
+
+	case *ast.StarExpr:
+		return "*" + g.typeString(t.X, file)
+
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + g.typeString(t.Elt, file)
+		}
+		if lit, ok := t.Len.(*ast.BasicLit); ok {
+			return "[" + lit.Value + "]" + g.typeString(t.Elt, file)
+		}
+
+	case *ast.MapType:
+		return "map[" + g.typeString(t.Key, file) + "]" + g.typeString(t.Value, file)
+
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			if path, found := imports(file)[x.Name]; found {
+				return g.addImport(path) + "." + t.Sel.Name
+			}
+		}
+
+	case *ast.InterfaceType:
+		if len(t.Methods.List) == 0 {
+			return "interface{}"
+		}
+
+	case *ast.StructType:
+		if len(t.Fields.List) == 0 {
+			return "struct{}"
+		}
+		var sb strings.Builder
+		sb.WriteString("struct {\n")
+		for _, field := range t.Fields.List {
+			names := make([]string, 0, len(field.Names))
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+			}
+			if len(names) > 0 {
+				sb.WriteString(strings.Join(names, ", ") + " ")
+			}
+			sb.WriteString(g.typeString(field.Type, file))
+			if field.Tag != nil {
+				sb.WriteString(" " + field.Tag.Value)
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("}")
+		return sb.String()
+	}
+
+	g.fail(fmt.Errorf("%s: unsupported type", g.src.fset.Position(expr.Pos())))
+	return "interface{}"
+}
+
+// localType returns the name in the client of a local type, queueing it to be copied
+func (g *generator) localType(name string) string {
+	if mapped, found := g.types[name]; found {
+		return mapped
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	mapped := string(r)
+	g.reserve(mapped)
+	g.types[name] = mapped
+	g.queue = append(g.queue, name)
+	return mapped
+}
+
+func (g *generator) addImport(path string) string {
+	if name, found := g.imports[path]; found {
+		return name
+	}
+	base := path[strings.LastIndex(path, "/")+1:]
+	name := base
+	for i := 2; g.importUsed(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	g.imports[path] = name
+	return name
+}
+
+func (g *generator) importUsed(name string) bool {
+	for _, used := range g.imports {
+		if used == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) reserve(name string) {
+	if g.names[name] {
+		g.fail(fmt.Errorf("the name %s is used more than once in the client", name))
+	}
+	g.names[name] = true
+}
+
+func (g *generator) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// isStd checks if the import-path is in the standard library
+func isStd(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
+
+// methodName converts a route-name to an exported method-name (ex: "get_item" -> "GetItem")
+func methodName(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			sb.WriteRune(r)
+		default:
+			upper = true
+		}
+	}
+	s := sb.String()
+	if s == "" || unicode.IsDigit([]rune(s)[0]) {
+		s = "Route" + s
+	}
+	return s
+}
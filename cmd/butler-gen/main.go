@@ -0,0 +1,59 @@
+// Command butler-gen generates a typed client from the []router.Route variable of a package.
+//
+// Each named route gets a method on the client, with a Params-struct holding the fields of
+// the handler-arguments. The fields are sent as path, query, header, cookie, form or body
+// according to their `from`-tag, in the same way as the router reads them.
+//
+// Usage:
+//
+//	//go:generate go run github.com/ninlil/butler/cmd/butler-gen -var routes -o itemsclient/client_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.dir, "dir", ".", "directory of the package with the routes")
+	flag.StringVar(&cfg.varName, "var", "routes", "name of the []router.Route variable")
+	flag.StringVar(&cfg.output, "o", "", "output file (default stdout)")
+	flag.StringVar(&cfg.pkgName, "pkg", "", "package name of the generated client (default the directory of the output file)")
+	flag.StringVar(&cfg.typeName, "type", "Client", "type name of the generated client")
+	flag.Parse()
+
+	if cfg.pkgName == "" {
+		if cfg.output == "" {
+			fmt.Fprintln(os.Stderr, "butler-gen: -pkg is required when writing to stdout")
+			os.Exit(2)
+		}
+		abs, err := filepath.Abs(cfg.output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "butler-gen: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.pkgName = filepath.Base(filepath.Dir(abs))
+	}
+
+	src, err := generate(&cfg, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "butler-gen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cfg.output == "" {
+		_, _ = os.Stdout.Write(src)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.output), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "butler-gen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(cfg.output, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "butler-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden-files")
+
+func TestGenerate(t *testing.T) {
+	var stderr bytes.Buffer
+	got, err := generate(&config{dir: "testdata/api", varName: "routes", pkgName: "apiclient", typeName: "Client"}, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const golden = "testdata/api.golden"
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code differs from %s (run with -update):\n%s", golden, got)
+	}
+
+	if !strings.Contains(stderr.String(), "has no name, skipped") {
+		t.Errorf("expected a warning for the unnamed route, got %q", stderr.String())
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	var stderr bytes.Buffer
+	if _, err := generate(&config{dir: "testdata/api", varName: "missing", pkgName: "x", typeName: "Client"}, &stderr); err == nil {
+		t.Error("expected an error for a missing variable")
+	}
+	if _, err := generate(&config{dir: "testdata/none", varName: "routes", pkgName: "x", typeName: "Client"}, &stderr); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestMethodName(t *testing.T) {
+	tests := map[string]string{
+		"get_item":   "GetItem",
+		"list-items": "ListItems",
+		"getAll":     "GetAll",
+		"v2.users":   "V2Users",
+		"404":        "Route404",
+	}
+	for name, want := range tests {
+		if got := methodName(name); got != want {
+			t.Errorf("methodName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
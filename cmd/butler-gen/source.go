@@ -0,0 +1,400 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	routerPath = "github.com/ninlil/butler/router"
+	authPath   = "github.com/ninlil/butler/auth"
+)
+
+// source is the parsed package with the routes
+type source struct {
+	fset  *token.FileSet
+	name  string
+	vars  map[string]decl
+	types map[string]decl
+	funcs map[string]decl
+}
+
+// decl is a declaration and the file it's in (to resolve the imports)
+type decl struct {
+	node ast.Node
+	file *ast.File
+}
+
+// routeDef is a route found in the []router.Route variable
+type routeDef struct {
+	name    string
+	method  string
+	path    string
+	handler *ast.FuncType
+	file    *ast.File
+}
+
+// fieldDef is a field of a handler-argument
+type fieldDef struct {
+	name     string
+	typ      ast.Expr
+	file     *ast.File
+	tag      string
+	from     string
+	json     string
+	required bool
+	optional bool // has a default-value, so the field is a pointer in the client
+}
+
+func loadSource(dir, skip string) (*source, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	skipAbs, _ := filepath.Abs(skip)
+
+	src := &source{
+		fset:  token.NewFileSet(),
+		vars:  make(map[string]decl),
+		types: make(map[string]decl),
+		funcs: make(map[string]decl),
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if abs, _ := filepath.Abs(path); skip != "" && abs == skipAbs {
+			continue
+		}
+
+		file, err := parser.ParseFile(src.fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if src.name == "" {
+			src.name = file.Name.Name
+		} else if file.Name.Name != src.name {
+			continue
+		}
+		src.add(file)
+	}
+	if src.name == "" {
+		return nil, fmt.Errorf("no go-files in %s", dir)
+	}
+	return src, nil
+}
+
+func (src *source) add(file *ast.File) {
+	for _, d := range file.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				src.funcs[d.Name.Name] = decl{d, file}
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					src.types[spec.Name.Name] = decl{spec, file}
+				case *ast.ValueSpec:
+					for i, name := range spec.Names {
+						if i < len(spec.Values) {
+							src.vars[name.Name] = decl{spec.Values[i], file}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// imports returns the import-path of each name in the file
+func imports(file *ast.File) map[string]string {
+	list := make(map[string]string, len(file.Imports))
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		list[name] = path
+	}
+	return list
+}
+
+// isSelector checks if expr is pkg.name where pkg is the import of path
+func isSelector(expr ast.Expr, file *ast.File, path, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	return ok && imports(file)[x.Name] == path
+}
+
+func (src *source) warn(w func(string, ...interface{}), pos token.Pos, format string, args ...interface{}) {
+	w("%s: %s", src.fset.Position(pos), fmt.Sprintf(format, args...))
+}
+
+// routes returns the routes of the variable
+func (src *source) routes(varName string, warn func(string, ...interface{})) ([]routeDef, error) {
+	d, found := src.vars[varName]
+	if !found {
+		return nil, fmt.Errorf("variable %q not found in package %s", varName, src.name)
+	}
+	return src.routeList(d.node.(ast.Expr), d.file, "", warn)
+}
+
+func (src *source) routeList(expr ast.Expr, file *ast.File, prefix string, warn func(string, ...interface{})) ([]routeDef, error) {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		if _, isSlice := e.Type.(*ast.ArrayType); !isSlice {
+			// a single route, as in append(routes, router.Route{...})
+			if rd, ok := src.route(e, file, prefix, warn); ok {
+				return []routeDef{rd}, nil
+			}
+			return nil, nil
+		}
+		var list []routeDef
+		for _, elt := range e.Elts {
+			if u, ok := elt.(*ast.UnaryExpr); ok && u.Op == token.AND {
+				elt = u.X
+			}
+			lit, ok := elt.(*ast.CompositeLit)
+			if !ok {
+				src.warn(warn, elt.Pos(), "route is not a literal, skipped")
+				continue
+			}
+			if rd, ok := src.route(lit, file, prefix, warn); ok {
+				list = append(list, rd)
+			}
+		}
+		return list, nil
+
+	case *ast.Ident:
+		if d, found := src.vars[e.Name]; found {
+			return src.routeList(d.node.(ast.Expr), d.file, prefix, warn)
+		}
+
+	case *ast.CallExpr:
+		if fn, ok := e.Fun.(*ast.Ident); ok && fn.Name == "append" {
+			var list []routeDef
+			for _, arg := range e.Args {
+				routes, err := src.routeList(arg, file, prefix, warn)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, routes...)
+			}
+			return list, nil
+		}
+		if isSelector(e.Fun, file, routerPath, "Group") && len(e.Args) >= 2 {
+			p, ok := stringLit(e.Args[0])
+			if !ok {
+				return nil, fmt.Errorf("%s: prefix of router.Group is not a string-literal", src.fset.Position(e.Pos()))
+			}
+			return src.routeList(e.Args[1], file, joinPath(prefix, p), warn)
+		}
+	}
+	return nil, fmt.Errorf("%s: unsupported routes-expression", src.fset.Position(expr.Pos()))
+}
+
+func (src *source) route(lit *ast.CompositeLit, file *ast.File, prefix string, warn func(string, ...interface{})) (routeDef, bool) {
+	rd := routeDef{method: "GET", file: file}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			src.warn(warn, lit.Pos(), "route without field-names, skipped")
+			return rd, false
+		}
+		key, _ := kv.Key.(*ast.Ident)
+		if key == nil {
+			continue
+		}
+		switch key.Name {
+		case "Name", "Method", "Path":
+			value, ok := stringLit(kv.Value)
+			if !ok {
+				src.warn(warn, kv.Pos(), "route %s is not a string-literal, skipped", key.Name)
+				return rd, false
+			}
+			switch key.Name {
+			case "Name":
+				rd.name = value
+			case "Method":
+				rd.method = strings.ToUpper(value)
+			case "Path":
+				rd.path = value
+			}
+
+		case "Handler":
+			switch h := kv.Value.(type) {
+			case *ast.Ident:
+				if d, found := src.funcs[h.Name]; found {
+					rd.handler = d.node.(*ast.FuncDecl).Type
+					rd.file = d.file
+				}
+			case *ast.FuncLit:
+				rd.handler = h.Type
+			}
+			if rd.handler == nil {
+				src.warn(warn, kv.Pos(), "handler is not a function in this package, skipped")
+				return rd, false
+			}
+		}
+	}
+
+	if rd.name == "" {
+		src.warn(warn, lit.Pos(), "route %s %s has no name, skipped", rd.method, rd.path)
+		return rd, false
+	}
+	if rd.handler == nil {
+		src.warn(warn, lit.Pos(), "route %q has no handler, skipped", rd.name)
+		return rd, false
+	}
+	rd.path = joinPath(prefix, rd.path)
+	return rd, true
+}
+
+// fields returns the fields of the struct-arguments of the handler
+func (src *source) fields(rd *routeDef, warn func(string, ...interface{})) []fieldDef {
+	var list []fieldDef
+	for _, param := range rd.handler.Params.List {
+		typ := param.Type
+		switch {
+		case isSelector(typ, rd.file, "context", "Context"),
+			isSelector(typ, rd.file, "net/http", "ResponseWriter"):
+			continue
+		}
+		if star, ok := typ.(*ast.StarExpr); ok {
+			if isSelector(star.X, rd.file, "net/http", "Request") {
+				continue
+			}
+			typ = star.X
+		}
+		if isSelector(typ, rd.file, routerPath, "ClientInfo") || isSelector(typ, rd.file, authPath, "Principal") {
+			continue
+		}
+
+		st, file := src.structType(typ, rd.file)
+		if st == nil {
+			src.warn(warn, param.Pos(), "route %q: unsupported argument, ignored", rd.name)
+			continue
+		}
+		list = append(list, src.structFields(st, file, rd.name, warn)...)
+	}
+	return list
+}
+
+func (src *source) structType(typ ast.Expr, file *ast.File) (*ast.StructType, *ast.File) {
+	switch t := typ.(type) {
+	case *ast.StructType:
+		return t, file
+	case *ast.Ident:
+		if d, found := src.types[t.Name]; found {
+			return src.structType(d.node.(*ast.TypeSpec).Type, d.file)
+		}
+	}
+	return nil, nil
+}
+
+func (src *source) structFields(st *ast.StructType, file *ast.File, route string, warn func(string, ...interface{})) []fieldDef {
+	var list []fieldDef
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			src.warn(warn, field.Pos(), "route %q: embedded field, ignored", route)
+			continue
+		}
+
+		var tag string
+		if field.Tag != nil {
+			tag, _ = strconv.Unquote(field.Tag.Value)
+		}
+		st := reflect.StructTag(tag)
+
+		for _, name := range field.Names {
+			fd := fieldDef{
+				name: name.Name,
+				typ:  field.Type,
+				file: file,
+				tag:  tag,
+				json: st.Get("json"),
+				from: st.Get("from"),
+			}
+			// the same rules as parseTag in the router
+			switch fd.from {
+			case "header", "query", "body", "cookie", "form":
+			default:
+				fd.from = "path"
+			}
+			var txt string
+			txt, fd.required = st.Lookup("required")
+			if flag, err := strconv.ParseBool(txt); err == nil && txt != "" {
+				fd.required = flag
+			}
+			_, hasDefault := st.Lookup("default")
+			if fd.from == "path" {
+				fd.required = true
+			} else if hasDefault && !fd.required {
+				fd.optional = true
+			}
+			list = append(list, fd)
+		}
+	}
+	return list
+}
+
+// result returns the type of the data-result of the handler, or nil if none
+func result(fn *ast.FuncType) ast.Expr {
+	if fn.Results == nil {
+		return nil
+	}
+	var data ast.Expr
+	for _, res := range fn.Results.List {
+		if id, ok := res.Type.(*ast.Ident); ok && (id.Name == "error" || id.Name == "int") {
+			continue
+		}
+		data = res.Type
+	}
+	return data
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// joinPath works as the joinPath of the router
+func joinPath(prefix, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return path
+	}
+	if path == "/" || path == "" {
+		return prefix
+	}
+	return prefix + path
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
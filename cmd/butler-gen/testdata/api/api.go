@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ninlil/butler/auth"
+	"github.com/ninlil/butler/router"
+)
+
+var routes = append(router.Group("/v1", v1),
+	router.Route{Name: "raw", Method: "GET", Path: "/raw", Handler: raw},
+	router.Route{Method: "GET", Path: "/unnamed", Handler: raw},
+)
+
+var v1 = []router.Route{
+	{Name: "get_item", Method: "GET", Path: "/items/{id}", Handler: getItem},
+	{Name: "list-items", Path: "/items", Handler: listItems},
+	{Name: "put_item", Method: "put", Path: "/items/{id}", Handler: putItem},
+	{Name: "any", Method: "*", Path: "/any", Handler: func(ctx context.Context) (int, error) { return 0, nil }},
+}
+
+type item struct {
+	ID      int       `json:"id"`
+	Tags    []tag     `json:"tags"`
+	Created time.Time `json:"created"`
+}
+
+type tag string
+
+type getArgs struct {
+	ID    int    `json:"id" from:"path" min:"1"`
+	Token string `json:"X-Token" from:"header" required:"true"`
+}
+
+func getItem(ctx context.Context, client router.ClientInfo, args *getArgs) (*item, int, error) {
+	return nil, 0, nil
+}
+
+type listArgs struct {
+	Limit   int           `json:"limit" from:"query" default:"10"`
+	Since   time.Duration `json:"since" from:"query"`
+	Session string        `json:"session" from:"cookie"`
+}
+
+func listItems(args listArgs) []item {
+	return nil
+}
+
+type putArgs struct {
+	ID   int   `json:"id" from:"path"`
+	Item *item `from:"body" required:"true"`
+}
+
+func putItem(w http.ResponseWriter, r *http.Request, user *auth.Principal, args *putArgs) error {
+	return nil
+}
+
+func raw(w http.ResponseWriter, r *http.Request) {}
@@ -0,0 +1,72 @@
+// Command butler-replay re-issues requests recorded by the router (see router.WithRecorder) against a
+// running service, and reports the responses that differ from the recorded ones.
+//
+// Usage:
+//
+//	butler-replay -url http://localhost:10000 [-H 'Authorization: Bearer ...'] [-compare Content-Type] recorded.jsonl
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ninlil/butler/replay"
+)
+
+type headerFlag http.Header
+
+func (h headerFlag) String() string { return "" }
+
+func (h headerFlag) Set(value string) error {
+	name, v, found := strings.Cut(value, ":")
+	if !found {
+		return fmt.Errorf("expected 'Name: value', got %q", value)
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(v))
+	return nil
+}
+
+func main() {
+	var opts replay.Options
+	opts.Header = make(http.Header)
+	baseURL := flag.String("url", "http://localhost:10000", "base-url of the service")
+	compare := flag.String("compare", "", "comma-separated response-headers to compare")
+	verbose := flag.Bool("v", false, "report all requests, not only the differing")
+	flag.Var(headerFlag(opts.Header), "H", "header to set on all requests, 'Name: value' (repeatable)")
+	flag.BoolVar(&opts.IgnoreBody, "ignore-body", false, "only compare the status (and -compare headers)")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "butler-replay: a file with recordings is required")
+		os.Exit(2)
+	}
+	if *compare != "" {
+		opts.CompareHeaders = strings.Split(*compare, ",")
+	}
+
+	var total, failed int
+	for _, path := range flag.Args() {
+		recordings, err := replay.Load(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "butler-replay: %v\n", err)
+			os.Exit(1)
+		}
+		for _, res := range replay.Replay(replay.Remote(*baseURL, nil), recordings, opts) {
+			total++
+			if !res.OK() {
+				failed++
+			}
+			if *verbose || !res.OK() {
+				fmt.Println(res.String())
+			}
+		}
+	}
+
+	fmt.Printf("%d of %d requests differ\n", failed, total)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
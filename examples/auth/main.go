@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/ninlil/butler"
+	"github.com/ninlil/butler/auth"
+	"github.com/ninlil/butler/log"
+	"github.com/ninlil/butler/router"
+)
+
+// Try:
+//
+//	curl -i http://localhost:10000/public
+//	curl -i http://localhost:10000/me
+//	curl -i -H "X-API-Key: secret" http://localhost:10000/me
+//	curl -i -u alice:password http://localhost:10000/me
+var routes = []router.Route{
+	{Name: "public", Method: "GET", Path: "/public", Handler: publicHandler, Auth: &router.AuthConfig{Disabled: true}},
+	{Name: "me", Method: "GET", Path: "/me", Handler: meHandler},
+}
+
+var authenticator = auth.Any(
+	&auth.APIKey{Lookup: auth.StaticKeys(map[string]auth.Principal{
+		"secret": {Subject: "batch-job", Roles: []string{"reader"}},
+	})},
+	&auth.Basic{Realm: "example", Check: auth.StaticUsers(
+		map[string]string{"alice": "password"},
+		map[string][]string{"alice": {"admin"}},
+	)},
+)
+
+func main() {
+	defer butler.Cleanup(nil)
+
+	err := router.Serve(routes,
+		router.WithPort(10000),
+		router.WithAuth(router.AuthConfig{Authenticator: authenticator}),
+	)
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
+	butler.Run()
+}
+
+func publicHandler() string {
+	return "this route is public"
+}
+
+func meHandler(user *auth.Principal) *auth.Principal {
+	return user
+}
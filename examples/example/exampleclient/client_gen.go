@@ -0,0 +1,381 @@
+// Code generated by butler-gen from main.routes; DO NOT EDIT.
+
+package exampleclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/ninlil/butler/client"
+)
+
+// Client calls the routes of main.routes
+type Client struct {
+	*client.Client
+}
+
+// New creates a Client, see client.New for the options
+func New(opts ...client.Option) *Client {
+	return &Client{Client: client.New(opts...)}
+}
+
+// Home calls the route "home" (GET /)
+func (c *Client) Home(ctx context.Context, opts ...client.CallOption) (string, error) {
+	var out string
+	var p client.Params
+	path, err := p.Path("/")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "GET", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// Null calls the route "null" (GET /null)
+func (c *Client) Null(ctx context.Context, opts ...client.CallOption) error {
+	var p client.Params
+	path, err := p.Path("/null")
+	if err != nil {
+		return err
+	}
+	return c.Call(ctx, "GET", path, p.Body(), nil, p.Options(opts...)...)
+}
+
+// GetAll calls the route "getAll" (GET /all)
+func (c *Client) GetAll(ctx context.Context, opts ...client.CallOption) ([]Article, error) {
+	var out []Article
+	var p client.Params
+	path, err := p.Path("/all")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "GET", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// GetRangeParams are the parameters of GetRange
+type GetRangeParams struct {
+	From int  `json:"from" from:"query" min:"0"`
+	To   *int `json:"to" from:"query" min:"0" default:"-1"`
+}
+
+// GetRange calls the route "getRange" (GET /range)
+func (c *Client) GetRange(ctx context.Context, params *GetRangeParams, opts ...client.CallOption) ([]Article, error) {
+	var out []Article
+	var p client.Params
+	if params != nil {
+		p.Add("query", "from", params.From, false)
+		p.Add("query", "to", params.To, false)
+	}
+	path, err := p.Path("/range")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "GET", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// GetItemParams are the parameters of GetItem
+type GetItemParams struct {
+	Index int `json:"index" from:"path" min:"0"`
+}
+
+// GetItem calls the route "getItem" (GET /item/{index})
+func (c *Client) GetItem(ctx context.Context, params *GetItemParams, opts ...client.CallOption) (*Article, error) {
+	var out *Article
+	var p client.Params
+	if params != nil {
+		p.Add("path", "index", params.Index, true)
+	}
+	path, err := p.Path("/item/{index}")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "GET", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// AddParams are the parameters of Add
+type AddParams struct {
+	Body *Article `from:"body"`
+}
+
+// Add calls the route "add" (POST /add)
+func (c *Client) Add(ctx context.Context, params *AddParams, opts ...client.CallOption) error {
+	var p client.Params
+	if params != nil {
+		p.Add("body", "", params.Body, false)
+	}
+	path, err := p.Path("/add")
+	if err != nil {
+		return err
+	}
+	return c.Call(ctx, "POST", path, p.Body(), nil, p.Options(opts...)...)
+}
+
+// TypesParams are the parameters of Types
+type TypesParams struct {
+	Int     int           `json:"int" from:"query"`
+	Int8    int8          `json:"int8" from:"query"`
+	Int16   int16         `json:"int16" from:"query"`
+	Int32   int32         `json:"int32" from:"query"`
+	Int64   int64         `json:"int64" from:"query"`
+	Text    string        `json:"string" from:"query"`
+	Float32 float32       `json:"float32" from:"query"`
+	Float64 float64       `json:"float64" from:"query"`
+	Bool    bool          `json:"bool" from:"query"`
+	Time    time.Time     `json:"time" from:"query"`
+	Dur     time.Duration `json:"dur" from:"query"`
+	Bytes   []byte        `json:"bytes" from:"query"`
+	Body    []byte        `from:"body"`
+}
+
+// Types calls the route "types" (* /types)
+func (c *Client) Types(ctx context.Context, method string, params *TypesParams, opts ...client.CallOption) (*DtReturn, error) {
+	var out *DtReturn
+	var p client.Params
+	if params != nil {
+		p.Add("query", "int", params.Int, false)
+		p.Add("query", "int8", params.Int8, false)
+		p.Add("query", "int16", params.Int16, false)
+		p.Add("query", "int32", params.Int32, false)
+		p.Add("query", "int64", params.Int64, false)
+		p.Add("query", "string", params.Text, false)
+		p.Add("query", "float32", params.Float32, false)
+		p.Add("query", "float64", params.Float64, false)
+		p.Add("query", "bool", params.Bool, false)
+		p.Add("query", "time", params.Time, false)
+		p.Add("query", "dur", params.Dur, false)
+		p.Add("query", "bytes", params.Bytes, false)
+		p.Add("body", "", params.Body, false)
+	}
+	path, err := p.Path("/types")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, method, path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// Sleep calls the route "sleep" (GET /sleep)
+func (c *Client) Sleep(ctx context.Context, opts ...client.CallOption) (string, error) {
+	var out string
+	var p client.Params
+	path, err := p.Path("/sleep")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "GET", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// Id calls the route "id" (GET /id)
+func (c *Client) Id(ctx context.Context, opts ...client.CallOption) (*TrackingResult, error) {
+	var out *TrackingResult
+	var p client.Params
+	path, err := p.Path("/id")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "GET", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// SumParams are the parameters of Sum
+type SumParams struct {
+	Sum *SumArgs `from:"body"`
+}
+
+// Sum calls the route "sum" (GET /sum)
+func (c *Client) Sum(ctx context.Context, params *SumParams, opts ...client.CallOption) (*HandlerResult, error) {
+	var out *HandlerResult
+	var p client.Params
+	if params != nil {
+		p.Add("body", "", params.Sum, false)
+	}
+	path, err := p.Path("/sum")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "GET", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// BodyMapParams are the parameters of BodyMap
+type BodyMapParams struct {
+	Body map[string]interface{} `from:"body"`
+}
+
+// BodyMap calls the route "body_map" (POST /body/map)
+func (c *Client) BodyMap(ctx context.Context, params *BodyMapParams, opts ...client.CallOption) ([]byte, error) {
+	var out []byte
+	var p client.Params
+	if params != nil {
+		p.Add("body", "", params.Body, false)
+	}
+	path, err := p.Path("/body/map")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "POST", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// BodyStructParams are the parameters of BodyStruct
+type BodyStructParams struct {
+	Body *BodyStructData `from:"body"`
+}
+
+// BodyStruct calls the route "body_struct" (POST /body/struct)
+func (c *Client) BodyStruct(ctx context.Context, params *BodyStructParams, opts ...client.CallOption) (string, error) {
+	var out string
+	var p client.Params
+	if params != nil {
+		p.Add("body", "", params.Body, false)
+	}
+	path, err := p.Path("/body/struct")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "POST", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// BodyBytesParams are the parameters of BodyBytes
+type BodyBytesParams struct {
+	Body []byte `from:"body"`
+}
+
+// BodyBytes calls the route "body_bytes" (POST /body/bytes)
+func (c *Client) BodyBytes(ctx context.Context, params *BodyBytesParams, opts ...client.CallOption) (string, error) {
+	var out string
+	var p client.Params
+	if params != nil {
+		p.Add("body", "", params.Body, false)
+	}
+	path, err := p.Path("/body/bytes")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "POST", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// BodyStringParams are the parameters of BodyString
+type BodyStringParams struct {
+	Body string `from:"body"`
+}
+
+// BodyString calls the route "body_string" (POST /body/string)
+func (c *Client) BodyString(ctx context.Context, params *BodyStringParams, opts ...client.CallOption) (string, error) {
+	var out string
+	var p client.Params
+	if params != nil {
+		p.Add("body", "", params.Body, false)
+	}
+	path, err := p.Path("/body/string")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "POST", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// BodyStringsParams are the parameters of BodyStrings
+type BodyStringsParams struct {
+	Body []string `from:"body"`
+}
+
+// BodyStrings calls the route "body_strings" (POST /body/strings)
+func (c *Client) BodyStrings(ctx context.Context, params *BodyStringsParams, opts ...client.CallOption) (string, error) {
+	var out string
+	var p client.Params
+	if params != nil {
+		p.Add("body", "", params.Body, false)
+	}
+	path, err := p.Path("/body/strings")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "POST", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// FormPostParams are the parameters of FormPost
+type FormPostParams struct {
+	Name    string `json:"name" from:"form"`
+	Email   string `json:"email" from:"form"`
+	Message string `json:"message" from:"form"`
+}
+
+// FormPost calls the route "form_post" (POST /form/post)
+func (c *Client) FormPost(ctx context.Context, params *FormPostParams, opts ...client.CallOption) (string, error) {
+	var out string
+	var p client.Params
+	if params != nil {
+		p.Add("form", "name", params.Name, false)
+		p.Add("form", "email", params.Email, false)
+		p.Add("form", "message", params.Message, false)
+	}
+	path, err := p.Path("/form/post")
+	if err != nil {
+		return out, err
+	}
+	err = c.Call(ctx, "POST", path, p.Body(), &out, p.Options(opts...)...)
+	return out, err
+}
+
+// Article is a copy of main.Article
+type Article struct {
+	Title   string `json:"title"`
+	Desc    string `json:"desc"`
+	Content string `json:"content"`
+}
+
+// DtReturn is a copy of main.dtReturn
+type DtReturn struct {
+	*DtArgs
+	XMLName     struct{} `json:"-" xml:"DatatypeArgs"`
+	BytesAsText string   `json:"bytes_as_text"`
+	DurAsText   string   `json:"dur_as_text"`
+	BodySize    int      `json:"BodySize"`
+}
+
+// TrackingResult is a copy of main.trackingResult
+type TrackingResult struct {
+	ReqID  string `json:"request"`
+	CorrID string `json:"correlation"`
+}
+
+// SumArgs is a copy of main.sumArgs
+type SumArgs struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// HandlerResult is a copy of main.handlerResult
+type HandlerResult struct {
+	Sum float64 `json:"sum"`
+}
+
+// BodyStructData is a copy of main.bodyStructData
+type BodyStructData struct {
+	Data string `json:"data"`
+}
+
+// DtArgs is a copy of main.dtArgs
+type DtArgs struct {
+	Int     int           `json:"int" from:"query"`
+	Int8    int8          `json:"int8" from:"query"`
+	Int16   int16         `json:"int16" from:"query"`
+	Int32   int32         `json:"int32" from:"query"`
+	Int64   int64         `json:"int64" from:"query"`
+	Text    string        `json:"string" from:"query"`
+	Float32 float32       `json:"float32" from:"query"`
+	Float64 float64       `json:"float64" from:"query"`
+	Bool    bool          `json:"bool" from:"query"`
+	Time    time.Time     `json:"time" from:"query"`
+	Dur     time.Duration `json:"dur" from:"query"`
+	Bytes   []byte        `json:"bytes" from:"query"`
+	Body    []byte        `from:"body"`
+}
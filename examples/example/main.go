@@ -10,6 +10,8 @@ import (
 	"github.com/ninlil/butler/router"
 )
 
+//go:generate go run github.com/ninlil/butler/cmd/butler-gen -var routes -o exampleclient/client_gen.go
+
 var routes = []router.Route{
 	{Name: "home", Method: "GET", Path: "/", Handler: homePage},
 	{Name: "null", Method: "GET", Path: "/null", Handler: nilFunc},
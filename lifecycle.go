@@ -49,7 +49,7 @@ func Run() {
 	runtime.OnClose("butler_close", butlerClose)
 
 	if workers.OnDone == workers.ReadyOnDone {
-		router.Ready = false
+		router.SetNotReady(router.NotReadyWorkers)
 	}
 
 	workersDone := workers.StartPending()
@@ -60,6 +60,8 @@ func Run() {
 		sig := <-sigs
 		log.Info().Msgf("!! Signal = %s", sig)
 
+		router.SetNotReady(router.NotReadyShutdown)
+
 		runtime.Close()
 		done <- true
 	}()
@@ -73,7 +75,7 @@ func Run() {
 			case workers.ContinueOnDone:
 				// nothing to do
 			case workers.ReadyOnDone:
-				router.Ready = true
+				router.ClearNotReady(router.NotReadyWorkers)
 			}
 		}()
 	}
@@ -0,0 +1,211 @@
+// Package replay re-issues requests recorded by the router (see router.WithRecorder) against a handler,
+// and compares the responses with the recorded ones, for regression-tests (see package replaytest)
+// or against a running service (see Remote).
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/ninlil/butler/router"
+)
+
+// Options of the replay
+type Options struct {
+	// Header is set on all requests, ex to replace redacted credentials
+	Header http.Header
+
+	// CompareHeaders are response-headers that must have the recorded values
+	CompareHeaders []string
+
+	// IgnoreBody only compares the status (and CompareHeaders) of the responses
+	IgnoreBody bool
+}
+
+// Result is the outcome of a single recording
+type Result struct {
+	Recording router.Recording
+	Status    int
+	Header    http.Header
+	Body      []byte
+
+	// Diffs are the differences from the recorded response, empty if it matches
+	Diffs []string
+
+	// Skipped is the reason the recording wasn't replayed, if so
+	Skipped string
+}
+
+// OK is true when the response matches the recording (or it was skipped)
+func (res *Result) OK() bool {
+	return len(res.Diffs) == 0
+}
+
+// String describes the result
+func (res *Result) String() string {
+	req := res.Recording.Request
+	switch {
+	case res.Skipped != "":
+		return fmt.Sprintf("%s %s: skipped, %s", req.Method, req.URL, res.Skipped)
+	case res.OK():
+		return fmt.Sprintf("%s %s: ok", req.Method, req.URL)
+	}
+	return fmt.Sprintf("%s %s: %s", req.Method, req.URL, strings.Join(res.Diffs, "; "))
+}
+
+// Load reads the recordings of a file
+func Load(path string) ([]router.Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return router.ReadRecordings(f)
+}
+
+// Replay sends the recorded requests to the handler, in order
+func Replay(h http.Handler, recordings []router.Recording, opts Options) []Result {
+	results := make([]Result, 0, len(recordings))
+	for _, rec := range recordings {
+		results = append(results, replayOne(h, rec, opts))
+	}
+	return results
+}
+
+func replayOne(h http.Handler, rec router.Recording, opts Options) Result {
+	res := Result{Recording: rec}
+	if rec.Request.Truncated {
+		res.Skipped = "the request-body was truncated when recorded"
+		return res
+	}
+
+	req, err := newRequest(rec.Request)
+	if err != nil {
+		res.Diffs = append(res.Diffs, err.Error())
+		return res
+	}
+	for name, values := range rec.Request.Header {
+		req.Header[name] = values
+	}
+	for name, values := range opts.Header {
+		req.Header[name] = values
+	}
+	// the recorded bodies are not compressed
+	req.Header.Del("Accept-Encoding")
+
+	w := &response{header: make(http.Header)}
+	h.ServeHTTP(w, req)
+	res.Status, res.Header, res.Body = w.status, w.header, w.body.Bytes()
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+
+	want := rec.Response
+	if res.Status != want.Status {
+		res.Diffs = append(res.Diffs, fmt.Sprintf("status %d, recorded %d", res.Status, want.Status))
+	}
+	for _, name := range opts.CompareHeaders {
+		if got, recorded := res.Header.Get(name), want.Header.Get(name); got != recorded {
+			res.Diffs = append(res.Diffs, fmt.Sprintf("header %s %q, recorded %q", name, got, recorded))
+		}
+	}
+	if !opts.IgnoreBody && !want.Truncated && !equalBodies(want.Header.Get("Content-Type"), want.Body, res.Body) {
+		res.Diffs = append(res.Diffs, fmt.Sprintf("body %q, recorded %q", shorten(res.Body), shorten(want.Body)))
+	}
+	return res
+}
+
+// newRequest creates the server-side request of a recording, like an incoming request
+func newRequest(rec router.RecordedRequest) (*http.Request, error) {
+	req, err := http.NewRequest(rec.Method, rec.URL, bytes.NewReader(rec.Body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	req.RequestURI = rec.URL
+	req.RemoteAddr = "192.0.2.1:1234"
+	if req.Host == "" {
+		req.Host = "example.com"
+	}
+	return req, nil
+}
+
+// response keeps the response of the handler
+type response struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *response) Header() http.Header {
+	return w.header
+}
+
+func (w *response) Write(buf []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(buf)
+}
+
+func (w *response) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// equalBodies compares JSON-bodies by value, and other bodies byte by byte
+func equalBodies(contentType string, a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return false
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func shorten(body []byte) string {
+	if len(body) > 200 {
+		return string(body[:200]) + "..."
+	}
+	return string(body)
+}
+
+// Remote is a handler forwarding the requests to a running service, to replay against it
+func Remote(baseURL string, client *http.Client) http.Handler {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, baseURL+r.URL.RequestURI(), r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		req.Header = r.Header.Clone()
+		resp, err := client.Do(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for name, values := range resp.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	})
+}
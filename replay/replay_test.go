@@ -0,0 +1,102 @@
+package replay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ninlil/butler/router"
+)
+
+type item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type itemArgs struct {
+	ID int `json:"id" from:"path"`
+}
+
+func newRouter(t *testing.T, name string, opts ...router.Option) *router.Router {
+	t.Helper()
+	routes := []router.Route{{Name: "item", Method: "GET", Path: "/items/{id}", Handler: func(args *itemArgs) item {
+		return item{ID: args.ID, Name: name}
+	}}}
+	r, err := router.New(routes, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// record serves the requests with a recorder, and writes the recordings to a file
+func record(t *testing.T, urls ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "recorded.jsonl")
+	sink, err := router.OpenRecordFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	r := newRouter(t, "first", router.WithRecorder(router.RecorderConfig{Sink: sink}))
+	for _, url := range urls {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	return path
+}
+
+func TestReplay(t *testing.T) {
+	path := record(t, "/items/1", "/items/2", "/missing")
+
+	recordings, err := Load(path)
+	if err != nil || len(recordings) != 3 {
+		t.Fatalf("Load: %d recordings, %v", len(recordings), err)
+	}
+
+	// the same handler gives the same responses
+	for _, res := range Replay(newRouter(t, "first"), recordings, Options{CompareHeaders: []string{"Content-Type"}}) {
+		if !res.OK() {
+			t.Error(res.String())
+		}
+	}
+
+	results := Replay(newRouter(t, "changed"), recordings, Options{})
+	if results[0].OK() || results[1].OK() || !results[2].OK() {
+		t.Errorf("results: %v, %v, %v", results[0].String(), results[1].String(), results[2].String())
+	}
+	if !strings.Contains(results[0].String(), "changed") {
+		t.Errorf("diff = %s", results[0].String())
+	}
+
+	results = Replay(newRouter(t, "changed"), recordings, Options{IgnoreBody: true})
+	for _, res := range results {
+		if !res.OK() {
+			t.Errorf("with IgnoreBody: %s", res.String())
+		}
+	}
+}
+
+func TestRemote(t *testing.T) {
+	path := record(t, "/items/1")
+	srv := httptest.NewServer(newRouter(t, "first"))
+	defer srv.Close()
+
+	recordings, _ := Load(path)
+	for _, res := range Replay(Remote(srv.URL, srv.Client()), recordings, Options{Header: http.Header{"X-Test": {"1"}}}) {
+		if !res.OK() {
+			t.Error(res.String())
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+// Package replaytest replays recordings in tests (see package replay):
+//
+//	func TestRegression(t *testing.T) {
+//		r, _ := router.New(routes)
+//		replaytest.Run(t, r, "testdata/recorded.jsonl", replay.Options{})
+//	}
+package replaytest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ninlil/butler/replay"
+)
+
+// Run replays the recordings of a file against the handler, reporting each mismatch as a test-error
+func Run(t testing.TB, h http.Handler, path string, opts replay.Options) {
+	t.Helper()
+	recordings, err := replay.Load(path)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	for _, res := range replay.Replay(h, recordings, opts) {
+		switch {
+		case res.Skipped != "":
+			t.Log(res.String())
+		case !res.OK():
+			t.Error(res.String())
+		}
+	}
+}
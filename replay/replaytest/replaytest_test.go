@@ -0,0 +1,58 @@
+package replaytest
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ninlil/butler/replay"
+	"github.com/ninlil/butler/router"
+)
+
+type itemArgs struct {
+	ID int `json:"id" from:"path"`
+}
+
+func newRouter(t *testing.T, name string, opts ...router.Option) *router.Router {
+	t.Helper()
+	routes := []router.Route{{Name: "item", Method: "GET", Path: "/items/{id}", Handler: func(args *itemArgs) string {
+		return fmt.Sprintf("%s %d", name, args.ID)
+	}}}
+	r, err := router.New(routes, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// errorsTB collects the errors of the test instead of failing it
+type errorsTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *errorsTB) Error(args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprint(args...))
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recorded.jsonl")
+	sink, err := router.OpenRecordFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newRouter(t, "first", router.WithRecorder(router.RecorderConfig{Sink: sink}))
+	for _, url := range []string{"/items/1", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+	sink.Close()
+
+	Run(t, newRouter(t, "first"), path, replay.Options{})
+
+	tb := &errorsTB{TB: t}
+	Run(tb, newRouter(t, "changed"), path, replay.Options{CompareHeaders: []string{"Content-Type"}})
+	if len(tb.errors) != 1 {
+		t.Errorf("errors = %q, want the changed response only", tb.errors)
+	}
+}
@@ -0,0 +1,249 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ninlil/butler/bufferedresponse"
+	"github.com/ninlil/butler/clock"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+)
+
+// AccessLogFormat selects how each request is written to the access-log
+type AccessLogFormat int
+
+// Access-log formats
+const (
+	// AccessLogDefault writes the selected fields with "METHOD path" as message
+	AccessLogDefault AccessLogFormat = iota
+
+	// AccessLogCombined writes the Apache combined log format as message
+	AccessLogCombined
+
+	// AccessLogJSON writes the fields of the common log format as json-fields
+	AccessLogJSON
+)
+
+// AccessLogField is an optional field in the access-log, combine several with |
+type AccessLogField int
+
+// Optional access-log fields
+const (
+	FieldRemoteIP AccessLogField = 1 << iota
+	FieldUserAgent
+	FieldReferer
+	FieldRoute
+	FieldBytesIn
+	FieldQuery
+
+	FieldAll = FieldRemoteIP | FieldUserAgent | FieldReferer | FieldRoute | FieldBytesIn | FieldQuery
+)
+
+// AccessLogConfig is the configuration of the access-log, see WithAccessLog
+type AccessLogConfig struct {
+	// Format of each log-entry (default AccessLogDefault)
+	Format AccessLogFormat
+
+	// Fields are the optional fields of the AccessLogDefault-format (default FieldRoute | FieldRemoteIP),
+	// duration, status and size are always included
+	Fields AccessLogField
+
+	// ExcludePaths are request-paths not logged, a trailing "*" matches any suffix (ex "/static/*")
+	ExcludePaths []string
+
+	// ExcludeRoutes are names of routes not logged
+	ExcludeRoutes []string
+
+	// ExcludeProbes skips requests to the health- and ready-paths, when served by routes of your own
+	// (the built-in probes are never logged)
+	ExcludeProbes bool
+
+	// SampleSuccess logs only every n:th successful request (status below 400), 0 or 1 logs all
+	SampleSuccess int
+
+	// Disabled turns the access-log off
+	Disabled bool
+}
+
+var defaultAccessLog AccessLogConfig
+
+// WithAccessLog configures the access-log (default is all requests with the route-name and client-ip)
+func WithAccessLog(config AccessLogConfig) Option {
+	return func(r *Router) error {
+		r.accessLog = &config
+		return nil
+	}
+}
+
+// skip checks if the request is excluded from the access-log
+func (cfg *AccessLogConfig) skip(r *Router, req *http.Request) bool {
+	path := req.URL.Path
+	if cfg.ExcludeProbes && path != "" && (path == r.healthPath || path == r.readyPath) {
+		return true
+	}
+	for _, pattern := range cfg.ExcludePaths {
+		if prefix, wildcard := strings.CutSuffix(pattern, "*"); wildcard {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == pattern {
+			return true
+		}
+	}
+	if len(cfg.ExcludeRoutes) > 0 {
+		if route := RouteFromRequest(req); route != nil && slices.Contains(cfg.ExcludeRoutes, route.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// countingReader counts the bytes read from the request-body
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func (r *Router) accessLogger(next http.Handler) http.Handler {
+	cfg := r.accessLog
+	if cfg == nil {
+		cfg = &defaultAccessLog
+	}
+	if cfg.Disabled {
+		return next
+	}
+	fields := cfg.Fields
+	if fields == 0 {
+		fields = FieldRoute | FieldRemoteIP
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if cfg.skip(r, req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		var body *countingReader
+		if req.Body != nil && (fields&FieldBytesIn != 0 || cfg.Format == AccessLogJSON) {
+			body = &countingReader{ReadCloser: req.Body}
+			req.Body = body
+		}
+
+		w2, _ := bufferedresponse.Get(w)
+		start := clock.Now()
+		next.ServeHTTP(w2, req)
+		dur := clock.Since(start)
+
+		status := w2.Status()
+		if status < 400 && cfg.SampleSuccess > 1 && (r.logged.Add(1)-1)%uint64(cfg.SampleSuccess) != 0 {
+			return
+		}
+
+		log := hlog.FromRequest(req)
+		var e *zerolog.Event
+		switch true {
+		case status < 200:
+			e = log.Debug()
+		case status < 400:
+			e = log.Info()
+		case status < 500:
+			e = log.Warn()
+		default:
+			e = log.Error()
+		}
+		if e == nil {
+			return
+		}
+
+		var bytesIn int64
+		if body != nil {
+			bytesIn = body.n
+		}
+
+		switch cfg.Format {
+		case AccessLogCombined:
+			e.Dur("duration", dur)
+			e.Msg(combinedLog(req, start, status, w2.Size()))
+
+		case AccessLogJSON:
+			e.Str("host", clientFromRequest(req).IP)
+			e.Str("user", remoteUser(req))
+			e.Str("method", req.Method)
+			e.Str("uri", req.URL.RequestURI())
+			e.Str("proto", req.Proto)
+			e.Int("status", status)
+			e.Int("bytes", w2.Size())
+			e.Int64("bytes_in", bytesIn)
+			e.Str("referer", req.Referer())
+			e.Str("user_agent", req.UserAgent())
+			e.Dur("duration", dur)
+			if route := RouteFromRequest(req); route != nil && route.Name != "" {
+				e.Str("route", route.Name)
+			}
+			e.Msg("")
+
+		default:
+			e.Dur("duration", dur)
+			e.Int("status", status)
+			e.Int("size", w2.Size())
+
+			if fields&FieldRemoteIP != 0 {
+				e.Str("ip", clientFromRequest(req).IP)
+			}
+			if fields&FieldUserAgent != 0 {
+				e.Str("user_agent", req.UserAgent())
+			}
+			if fields&FieldReferer != 0 {
+				e.Str("referer", req.Referer())
+			}
+			if fields&FieldBytesIn != 0 {
+				e.Int64("bytes_in", bytesIn)
+			}
+			if fields&FieldQuery != 0 && req.URL.RawQuery != "" {
+				e.Str("query", req.URL.RawQuery)
+			}
+			if route := RouteFromRequest(req); fields&FieldRoute != 0 && route != nil && route.Name != "" {
+				e.Str("route", route.Name)
+			}
+
+			e.Msgf("%s %s", req.Method, req.URL.Path)
+		}
+	})
+}
+
+// combinedLog formats the request in the Apache combined log format
+func combinedLog(req *http.Request, start time.Time, status, size int) string {
+	bytes := "-"
+	if size > 0 {
+		bytes = fmt.Sprint(size)
+	}
+	return fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s %q %q`,
+		clientFromRequest(req).IP, remoteUser(req), start.Format("02/Jan/2006:15:04:05 -0700"),
+		req.Method, req.URL.RequestURI(), req.Proto, status, bytes,
+		orDash(req.Referer()), orDash(req.UserAgent()))
+}
+
+func remoteUser(req *http.Request) string {
+	if user, _, ok := req.BasicAuth(); ok && user != "" {
+		return user
+	}
+	return "-"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
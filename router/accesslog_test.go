@@ -0,0 +1,205 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+)
+
+// captureAccessLog serves the requests and returns the log-entries
+func captureAccessLog(t *testing.T, routes []Route, opts []Option, reqs ...*http.Request) []map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	orgLogger := zlog.Logger
+	zlog.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { zlog.Logger = orgLogger })
+
+	h := buildTestHandlerWithOpts(t, routes, opts...)
+	for _, req := range reqs {
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log-line %q: %v", line, err)
+		}
+		if entry["level"] != "trace" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func TestAccessLogFields(t *testing.T) {
+	req := httptest.NewRequest("POST", "/items?x=1", strings.NewReader(`{"name":"apple"}`))
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Referer", "http://example.com/")
+
+	entries := captureAccessLog(t,
+		[]Route{{Name: "add", Method: "POST", Path: "/items", Handler: handlerBody}},
+		[]Option{WithAccessLog(AccessLogConfig{Fields: FieldAll})},
+		req)
+	if len(entries) != 1 {
+		t.Fatalf("got %d log-entries, want 1", len(entries))
+	}
+
+	want := map[string]interface{}{
+		"ip":         "192.0.2.1",
+		"user_agent": "test-agent",
+		"referer":    "http://example.com/",
+		"route":      "add",
+		"bytes_in":   float64(16),
+		"query":      "x=1",
+		"status":     float64(200),
+		"message":    "POST /items",
+	}
+	for key, value := range want {
+		if entries[0][key] != value {
+			t.Errorf("%s = %v, want %v", key, entries[0][key], value)
+		}
+	}
+}
+
+func TestAccessLogDefault(t *testing.T) {
+	entries := captureAccessLog(t,
+		[]Route{{Name: "status", Method: "GET", Path: "/status", Handler: handlerReturnStatus}},
+		nil,
+		httptest.NewRequest("GET", "/status", nil))
+	if len(entries) != 1 {
+		t.Fatalf("got %d log-entries, want 1", len(entries))
+	}
+	if entries[0]["route"] != "status" {
+		t.Errorf("route = %v, want %q", entries[0]["route"], "status")
+	}
+	if _, found := entries[0]["user_agent"]; found {
+		t.Error("user_agent should not be logged by default")
+	}
+}
+
+func TestAccessLogExclude(t *testing.T) {
+	routes := []Route{
+		{Name: "health", Method: "GET", Path: "/healthz", Handler: handlerReturnStatus},
+		{Name: "metrics", Method: "GET", Path: "/metrics", Handler: handlerReturnStatus},
+		{Name: "static", Method: "GET", Path: "/static/*", Handler: handlerWildcardValue},
+		{Name: "item", Method: "GET", Path: "/items/{id}", Handler: handlerPathParam},
+	}
+	entries := captureAccessLog(t, routes,
+		[]Option{WithAccessLog(AccessLogConfig{
+			ExcludeProbes: true,
+			ExcludePaths:  []string{"/static/*"},
+			ExcludeRoutes: []string{"metrics"},
+		})},
+		httptest.NewRequest("GET", "/healthz", nil),
+		httptest.NewRequest("GET", "/metrics", nil),
+		httptest.NewRequest("GET", "/static/app.js", nil),
+		httptest.NewRequest("GET", "/items/1", nil),
+	)
+	if len(entries) != 1 || entries[0]["route"] != "item" {
+		t.Errorf("got %v, want only the item-route", entries)
+	}
+}
+
+func TestAccessLogSample(t *testing.T) {
+	var reqs []*http.Request
+	for i := 0; i < 6; i++ {
+		reqs = append(reqs, httptest.NewRequest("GET", "/ok", nil))
+	}
+	reqs = append(reqs, httptest.NewRequest("GET", "/fail", nil))
+
+	entries := captureAccessLog(t,
+		[]Route{
+			{Name: "ok", Method: "GET", Path: "/ok", Handler: handlerReturnStatus},
+			{Name: "fail", Method: "GET", Path: "/fail", Handler: handlerReturnError},
+		},
+		[]Option{WithAccessLog(AccessLogConfig{SampleSuccess: 3})},
+		reqs...)
+
+	var ok, fail int
+	for _, entry := range entries {
+		switch entry["route"] {
+		case "ok":
+			ok++
+		case "fail":
+			fail++
+		}
+	}
+	if ok != 2 || fail != 1 {
+		t.Errorf("logged %d successful and %d failed, want 2 and 1", ok, fail)
+	}
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	req := httptest.NewRequest("GET", "/status?a=b", nil)
+	req.SetBasicAuth("alice", "secret")
+	req.Header.Set("User-Agent", "test-agent")
+
+	entries := captureAccessLog(t,
+		[]Route{{Name: "status", Method: "GET", Path: "/status", Handler: handlerReturnStatus}},
+		[]Option{WithAccessLog(AccessLogConfig{Format: AccessLogCombined})},
+		req)
+	if len(entries) != 1 {
+		t.Fatalf("got %d log-entries, want 1", len(entries))
+	}
+	msg, _ := entries[0]["message"].(string)
+	if !strings.HasPrefix(msg, "192.0.2.1 - alice [") || !strings.HasSuffix(msg, `] "GET /status?a=b HTTP/1.1" 201 - "-" "test-agent"`) {
+		t.Errorf("message = %q", msg)
+	}
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	entries := captureAccessLog(t,
+		[]Route{{Name: "item", Method: "GET", Path: "/items/{id}", Handler: handlerPathParam}},
+		[]Option{WithAccessLog(AccessLogConfig{Format: AccessLogJSON})},
+		httptest.NewRequest("GET", "/items/3?x=1", nil))
+	if len(entries) != 1 {
+		t.Fatalf("got %d log-entries, want 1", len(entries))
+	}
+	want := map[string]interface{}{
+		"host":   "192.0.2.1",
+		"user":   "-",
+		"method": "GET",
+		"uri":    "/items/3?x=1",
+		"proto":  "HTTP/1.1",
+		"status": float64(200),
+		"route":  "item",
+	}
+	for key, value := range want {
+		if entries[0][key] != value {
+			t.Errorf("%s = %v, want %v", key, entries[0][key], value)
+		}
+	}
+}
+
+func TestAccessLogDisabled(t *testing.T) {
+	entries := captureAccessLog(t,
+		[]Route{{Name: "status", Method: "GET", Path: "/status", Handler: handlerReturnStatus}},
+		[]Option{WithAccessLog(AccessLogConfig{Disabled: true})},
+		httptest.NewRequest("GET", "/status", nil))
+	if len(entries) != 0 {
+		t.Errorf("got %v, want no log-entries", entries)
+	}
+}
+
+func TestAccessLogClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+
+	entries := captureAccessLog(t,
+		[]Route{{Name: "status", Method: "GET", Path: "/status", Handler: handlerReturnStatus}},
+		[]Option{WithTrustedProxies("192.0.2.0/24")},
+		req)
+	if len(entries) != 1 || entries[0]["ip"] != "198.51.100.7" {
+		t.Errorf("got %v, want the forwarded client-ip", entries)
+	}
+}
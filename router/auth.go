@@ -0,0 +1,92 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ninlil/butler/auth"
+	"github.com/ninlil/butler/log"
+)
+
+// AuthConfig is the authentication of a router (see WithAuth) or a single route
+type AuthConfig struct {
+	// Authenticator verifies the credentials of the requests, use auth.Any to accept several kinds
+	Authenticator auth.Authenticator
+
+	// Optional lets requests without credentials through, without a principal
+	// (invalid credentials are still rejected)
+	Optional bool
+
+	// Disabled turns authentication off, used to exclude a single route from the router-wide configuration
+	Disabled bool
+}
+
+// WithAuth requires authentication on all routes, use Route.Auth to override for a single route
+func WithAuth(config AuthConfig) Option {
+	return func(r *Router) error {
+		if err := config.validate(); err != nil {
+			return err
+		}
+		r.auth = &config
+		return nil
+	}
+}
+
+func (cfg *AuthConfig) validate() error {
+	if cfg != nil && !cfg.Disabled && cfg.Authenticator == nil {
+		return ErrorInvalidAuth
+	}
+	return nil
+}
+
+// authConfig returns the active authentication for the route, or nil if none
+func (rt *Route) authConfig() *AuthConfig {
+	cfg := rt.Auth
+	if cfg == nil && rt.router != nil {
+		cfg = rt.router.auth
+	}
+	if cfg == nil || cfg.Disabled {
+		return nil
+	}
+	return cfg
+}
+
+// handler is the middleware authenticating the requests of a route, adding the principal to the context
+func (cfg *AuthConfig) handler(rt *Route) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := cfg.Authenticator.Authenticate(r)
+			switch {
+			case err == nil && p != nil:
+				next.ServeHTTP(w, r.WithContext(auth.CtxWithPrincipal(r.Context(), p)))
+				return
+
+			case (err == nil || errors.Is(err, auth.ErrNoCredentials)) && cfg.Optional:
+				next.ServeHTTP(w, r)
+				return
+
+			case err == nil || errors.Is(err, auth.ErrNoCredentials):
+				err = ErrUnauthorized
+
+			case errors.Is(err, auth.ErrInvalidCredentials):
+				log.FromCtx(r.Context()).Debug().Msgf("router: authentication failed: %v", err)
+				err = auth.ErrInvalidCredentials // the details are only logged
+
+			default:
+				log.FromCtx(r.Context()).Warn().Msgf("router: authentication failed: %v", err)
+				err = auth.ErrInvalidCredentials
+			}
+
+			if challenge := cfg.Authenticator.Challenge(); challenge != "" {
+				w.Header().Set("WWW-Authenticate", challenge)
+			}
+			rt.writeError(err, w, r, http.StatusUnauthorized)
+		})
+	}
+}
+
+// principalFromRequest returns the principal of the request, or nil
+func principalFromRequest(r *http.Request) *auth.Principal {
+	p, _ := auth.PrincipalFromCtx(r.Context())
+	return p
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ninlil/butler/auth"
+)
+
+func handlerPrincipal(p auth.Principal, ptr *auth.Principal) string {
+	if ptr == nil {
+		return "anonymous"
+	}
+	return p.Subject + "/" + ptr.Method
+}
+
+func TestAuth(t *testing.T) {
+	keys := &auth.APIKey{Lookup: auth.StaticKeys(map[string]auth.Principal{"secret": {Subject: "svc"}})}
+	routes := []Route{
+		{Name: "private", Method: "GET", Path: "/private", Handler: handlerPrincipal},
+		{Name: "public", Method: "GET", Path: "/public", Handler: handlerPrincipal, Auth: &AuthConfig{Disabled: true}},
+		{Name: "optional", Method: "GET", Path: "/optional", Handler: handlerPrincipal,
+			Auth: &AuthConfig{Authenticator: keys, Optional: true}},
+	}
+	h := buildTestHandlerWithOpts(t, routes, WithAuth(AuthConfig{Authenticator: keys}))
+
+	tests := []struct {
+		name       string
+		path       string
+		key        string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid key", "/private", "secret", http.StatusOK, "svc/apikey"},
+		{"missing key", "/private", "", http.StatusUnauthorized, `{"error":"unauthorized"}`},
+		{"wrong key", "/private", "wrong", http.StatusUnauthorized, `{"error":"invalid credentials"}`},
+		{"public", "/public", "", http.StatusOK, "anonymous"},
+		{"optional without key", "/optional", "", http.StatusOK, "anonymous"},
+		{"optional with key", "/optional", "secret", http.StatusOK, "svc/apikey"},
+		{"optional with wrong key", "/optional", "wrong", http.StatusUnauthorized, `{"error":"invalid credentials"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.path, nil)
+			if tc.wantStatus == http.StatusOK {
+				req.Header.Set("Accept", "text/plain")
+			}
+			if tc.key != "" {
+				req.Header.Set("X-API-Key", tc.key)
+			}
+			h.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus || w.Body.String() != tc.wantBody {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tc.wantStatus, tc.wantBody)
+			}
+			if tc.wantStatus == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != `APIKey header="X-API-Key"` {
+				t.Errorf("WWW-Authenticate = %q", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestWithAuthInvalid(t *testing.T) {
+	if _, err := New(nil, WithAuth(AuthConfig{})); err != ErrorInvalidAuth {
+		t.Errorf("err = %v, want %v", err, ErrorInvalidAuth)
+	}
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/ninlil/butler/auth"
+)
+
+var tErrorType = reflect.TypeOf(new(error)).Elem()
+
+// errPolicyNotAFunc is when Route.Policy isn't a func returning an error
+type errPolicyNotAFunc Route
+
+func (e errPolicyNotAFunc) Error() string {
+	return fmt.Sprintf("error: policy for %s '%s' is not a function returning an error", e.Method, e.Path)
+}
+
+// initPolicy checks the policy of the route
+func (rt *Route) initPolicy() error {
+	if rt.Policy == nil {
+		return nil
+	}
+	rt.policyType = reflect.TypeOf(rt.Policy)
+	rt.policyValue = reflect.ValueOf(rt.Policy)
+	if rt.policyType.Kind() != reflect.Func || rt.policyType.NumOut() != 1 || rt.policyType.Out(0) != tErrorType {
+		return errPolicyNotAFunc(*rt)
+	}
+	return nil
+}
+
+// authorize is the middleware checking the required roles and scopes of the route
+func (rt *Route) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := principalFromRequest(r)
+		if p == nil {
+			rt.writeError(ErrUnauthorized, w, r, http.StatusUnauthorized)
+			return
+		}
+		if err := rt.checkPrincipal(p); err != nil {
+			rt.writeError(err, w, r, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkPrincipal checks that the principal has any of the roles, and all of the scopes
+func (rt *Route) checkPrincipal(p *auth.Principal) error {
+	if len(rt.Roles) > 0 {
+		var found bool
+		for _, role := range rt.Roles {
+			found = found || p.HasRole(role)
+		}
+		if !found {
+			return fmt.Errorf("%w: requires the role %s", ErrForbidden, strings.Join(rt.Roles, " or "))
+		}
+	}
+	var missing []string
+	for _, scope := range rt.Scopes {
+		if !p.HasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: requires the scope %s", ErrForbidden, strings.Join(missing, " and "))
+	}
+	return nil
+}
+
+// checkPolicy calls the policy of the route, with the bound arguments of the handler (if any)
+// and the same kind of arguments as a handler for the rest. The status is 0 when allowed.
+func (rt *Route) checkPolicy(w http.ResponseWriter, r *http.Request, bound []reflect.Value) (int, error) {
+	if rt.Policy == nil {
+		return 0, nil
+	}
+
+	n := rt.policyType.NumIn()
+	args := make([]reflect.Value, n)
+next:
+	for i := 0; i < n; i++ {
+		arg := rt.policyType.In(i)
+		for _, v := range bound {
+			if v.IsValid() && v.Type() == arg {
+				args[i] = v
+				continue next
+			}
+		}
+		v, err := rt.createArg(arg, w, r)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if !v.IsValid() {
+			v = reflect.Zero(arg)
+		}
+		args[i] = v
+	}
+
+	res := rt.policyValue.Call(args)[0]
+	if res.IsNil() {
+		return 0, nil
+	}
+	err := res.Interface().(error)
+	if errors.Is(err, ErrUnauthorized) {
+		return http.StatusUnauthorized, err
+	}
+	return http.StatusForbidden, err
+}
@@ -0,0 +1,110 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ninlil/butler/auth"
+)
+
+type tenantArgs struct {
+	Tenant string `json:"tenant" from:"path"`
+}
+
+func handlerTenant(args *tenantArgs) string {
+	return args.Tenant
+}
+
+// tenantPolicy allows the caller to access its own tenant only
+func tenantPolicy(args *tenantArgs, p *auth.Principal) error {
+	if p == nil {
+		return ErrUnauthorized
+	}
+	if p.Claims["tenant"] != args.Tenant {
+		return fmt.Errorf("%w: wrong tenant", ErrForbidden)
+	}
+	return nil
+}
+
+func handlerRaw(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("raw"))
+}
+
+func TestAuthorization(t *testing.T) {
+	keys := &auth.APIKey{Lookup: auth.StaticKeys(map[string]auth.Principal{
+		"admin":  {Subject: "root", Roles: []string{"admin"}, Scopes: []string{"read", "write"}},
+		"reader": {Subject: "bob", Roles: []string{"user"}, Scopes: []string{"read"}},
+		"acme":   {Subject: "alice", Claims: map[string]interface{}{"tenant": "acme"}},
+	})}
+	rawPolicy := func(r *http.Request) error {
+		if r.URL.Query().Get("deny") != "" {
+			return errors.New("denied")
+		}
+		return nil
+	}
+	routes := []Route{
+		{Name: "admin", Method: "GET", Path: "/admin", Handler: handlerPrincipal, Roles: []string{"admin", "operator"}},
+		{Name: "write", Method: "GET", Path: "/write", Handler: handlerPrincipal, Scopes: []string{"read", "write"}},
+		{Name: "tenant", Method: "GET", Path: "/tenants/{tenant}", Handler: handlerTenant, Policy: tenantPolicy,
+			Auth: &AuthConfig{Authenticator: keys, Optional: true}},
+		{Name: "raw", Method: "GET", Path: "/raw", Handler: handlerRaw, Policy: rawPolicy},
+	}
+	h := buildTestHandlerWithOpts(t, routes, WithAuth(AuthConfig{Authenticator: keys}))
+
+	tests := []struct {
+		name       string
+		path       string
+		key        string
+		wantStatus int
+		wantBody   string
+	}{
+		{"role", "/admin", "admin", http.StatusOK, "root/apikey"},
+		{"missing role", "/admin", "reader", http.StatusForbidden, `{"error":"forbidden: requires the role admin or operator"}`},
+		{"scopes", "/write", "admin", http.StatusOK, "root/apikey"},
+		{"missing scope", "/write", "reader", http.StatusForbidden, `{"error":"forbidden: requires the scope write"}`},
+		{"own tenant", "/tenants/acme", "acme", http.StatusOK, "acme"},
+		{"other tenant", "/tenants/other", "acme", http.StatusForbidden, `{"error":"forbidden: wrong tenant"}`},
+		{"tenant without principal", "/tenants/acme", "", http.StatusUnauthorized, `{"error":"unauthorized"}`},
+		{"raw allowed", "/raw", "reader", http.StatusOK, "raw"},
+		{"raw denied", "/raw?deny=1", "reader", http.StatusForbidden, `{"error":"denied"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.path, nil)
+			if tc.wantStatus == http.StatusOK {
+				req.Header.Set("Accept", "text/plain")
+			}
+			if tc.key != "" {
+				req.Header.Set("X-API-Key", tc.key)
+			}
+			h.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus || w.Body.String() != tc.wantBody {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tc.wantStatus, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestAuthorizationWithoutPrincipal(t *testing.T) {
+	routes := []Route{{Name: "admin", Method: "GET", Path: "/admin", Handler: handlerPrincipal, Roles: []string{"admin"}}}
+	h := buildTestHandler(t, routes)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/admin", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPolicyInvalid(t *testing.T) {
+	for _, policy := range []interface{}{"not a func", func() {}, func() bool { return true }} {
+		rt := Route{Method: "GET", Path: "/x", Handler: handlerPrincipal, Policy: policy}
+		if _, err := new(Router).newRoute(rt); err == nil {
+			t.Errorf("policy %T: expected an error", policy)
+		}
+	}
+}
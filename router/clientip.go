@@ -0,0 +1,205 @@
+package router
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientInfo is the client of a request, as reported by any trusted proxies (see WithTrustedProxies).
+// It can be used as a handler-argument (ClientInfo or *ClientInfo).
+type ClientInfo struct {
+	IP     string // the ip-address of the client
+	Scheme string // "http" or "https", as used by the client
+}
+
+// WithTrustedProxies sets the proxies (ip-addresses or CIDRs, ex "10.0.0.0/8") whose forwarding-headers
+// ('Forwarded', 'X-Forwarded-For', 'X-Forwarded-Proto' and 'X-Real-IP') are used to find the client.
+// Without trusted proxies the client is the remote address of the connection.
+func WithTrustedProxies(cidrs ...string) Option {
+	return func(r *Router) error {
+		for _, cidr := range cidrs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				addr, err2 := netip.ParseAddr(cidr)
+				if err2 != nil {
+					return ErrorInvalidProxy
+				}
+				prefix = netip.PrefixFrom(addr, addr.BitLen())
+			}
+			r.trustedProxies = append(r.trustedProxies, prefix.Masked())
+		}
+		return nil
+	}
+}
+
+func (r *Router) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHop is a single proxy-hop from the forwarding-headers
+type forwardedHop struct {
+	addr  netip.Addr
+	valid bool
+	proto string
+}
+
+// clientInfo finds the client of the request, walking the forwarding-headers from the nearest
+// proxy until an address that isn't trusted
+func (r *Router) clientInfo(req *http.Request) ClientInfo {
+	ci := ClientInfo{IP: remoteAddr(req), Scheme: "http"}
+	if req.TLS != nil {
+		ci.Scheme = "https"
+	}
+
+	peer, err := netip.ParseAddr(ci.IP)
+	if err != nil || !r.trusted(peer) {
+		return ci
+	}
+
+	hops := forwardedHops(req)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if !hop.valid {
+			break
+		}
+		ci.IP = hop.addr.Unmap().String()
+		if hop.proto != "" {
+			ci.Scheme = hop.proto
+		}
+		if !r.trusted(hop.addr) {
+			break
+		}
+	}
+	return ci
+}
+
+// parseProto returns the forwarded scheme, or "" for anything but http and https
+func parseProto(value string) string {
+	switch proto := strings.ToLower(strings.TrimSpace(value)); proto {
+	case "http", "https":
+		return proto
+	}
+	return ""
+}
+
+// forwardedHops reads the hops from the 'Forwarded' header, or the 'X-Forwarded-*' headers
+// or the 'X-Real-IP' header, in that order
+func forwardedHops(req *http.Request) []forwardedHop {
+	var hops []forwardedHop
+
+	if values := req.Header.Values("Forwarded"); len(values) > 0 {
+		for _, element := range strings.Split(strings.Join(values, ","), ",") {
+			var hop forwardedHop
+			for _, pair := range strings.Split(element, ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				value = strings.Trim(value, `"`)
+				switch strings.ToLower(key) {
+				case "for":
+					hop.addr, hop.valid = parseHopAddr(value)
+				case "proto":
+					hop.proto = parseProto(value)
+				}
+			}
+			hops = append(hops, hop)
+		}
+		return hops
+	}
+
+	if values := req.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		var protos []string
+		if p := req.Header.Values("X-Forwarded-Proto"); len(p) > 0 {
+			protos = strings.Split(strings.Join(p, ","), ",")
+		}
+		list := strings.Split(strings.Join(values, ","), ",")
+		for i, value := range list {
+			var hop forwardedHop
+			hop.addr, hop.valid = parseHopAddr(value)
+			switch {
+			case len(protos) == len(list):
+				hop.proto = protos[i]
+			case len(protos) > 0 && i == len(list)-1:
+				hop.proto = protos[len(protos)-1]
+			}
+			hop.proto = parseProto(hop.proto)
+			hops = append(hops, hop)
+		}
+		return hops
+	}
+
+	if value := req.Header.Get("X-Real-IP"); value != "" {
+		var hop forwardedHop
+		hop.addr, hop.valid = parseHopAddr(value)
+		hop.proto = parseProto(req.Header.Get("X-Forwarded-Proto"))
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// parseHopAddr parses an address with an optional port, ipv6 may be in brackets
+func parseHopAddr(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	addr, err := netip.ParseAddr(value)
+	return addr, err == nil
+}
+
+func remoteAddr(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
+
+// clientHandler adds the ClientInfo to the context of the request
+func (r *Router) clientHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ci := r.clientInfo(req)
+		next.ServeHTTP(w, req.WithContext(CtxWithClient(req.Context(), ci)))
+	})
+}
+
+// Handle the client-info...
+type clientKey struct{}
+
+// ClientFromCtx returns the client associated to the context if any.
+func ClientFromCtx(ctx context.Context) (ClientInfo, bool) {
+	ci, ok := ctx.Value(clientKey{}).(ClientInfo)
+	return ci, ok
+}
+
+// ClientIPFromCtx returns the ip-address of the client associated to the context if any.
+func ClientIPFromCtx(ctx context.Context) (ip string) {
+	if ci, ok := ClientFromCtx(ctx); ok {
+		return ci.IP
+	}
+	return
+}
+
+// CtxWithClient adds the given client-info to the context
+func CtxWithClient(ctx context.Context, ci ClientInfo) context.Context {
+	return context.WithValue(ctx, clientKey{}, ci)
+}
+
+// clientFromRequest returns the client from the context, or the remote address if none
+func clientFromRequest(req *http.Request) ClientInfo {
+	if ci, ok := ClientFromCtx(req.Context()); ok {
+		return ci
+	}
+	ci := ClientInfo{IP: remoteAddr(req), Scheme: "http"}
+	if req.TLS != nil {
+		ci.Scheme = "https"
+	}
+	return ci
+}
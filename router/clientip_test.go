@@ -0,0 +1,93 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientInfo(t *testing.T) {
+	r, err := New(nil, WithTrustedProxies("10.0.0.0/8", "192.0.2.1", "2001:db8::/32"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remote     string
+		headers    map[string]string
+		wantIP     string
+		wantScheme string
+	}{
+		{"direct", "203.0.113.5:1234", nil, "203.0.113.5", "http"},
+		{"untrusted peer", "203.0.113.5:1234", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "203.0.113.5", "http"},
+		{"xff", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Forwarded-Proto": "https"}, "1.2.3.4", "https"},
+		{"xff chain", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "6.6.6.6, 1.2.3.4, 10.0.0.2"}, "1.2.3.4", "http"},
+		{"xff all trusted", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3", "http"},
+		{"xff invalid", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "garbage, 10.0.0.2"}, "10.0.0.2", "http"},
+		{"forwarded", "192.0.2.1:80", map[string]string{"Forwarded": `for=198.51.100.7;proto=https, for="[2001:db8:cafe::17]:4711"`}, "198.51.100.7", "https"},
+		{"forwarded ipv6", "192.0.2.1:80", map[string]string{"Forwarded": `for="[2001:db9::1]:4711";proto=HTTPS`}, "2001:db9::1", "https"},
+		{"forwarded first", "192.0.2.1:80", map[string]string{"Forwarded": "for=198.51.100.7", "X-Forwarded-For": "1.2.3.4"}, "198.51.100.7", "http"},
+		{"xff proto upper-case", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Forwarded-Proto": " HTTPS "}, "1.2.3.4", "https"},
+		{"xff proto invalid", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Forwarded-Proto": "javascript"}, "1.2.3.4", "http"},
+		{"forwarded proto invalid", "192.0.2.1:80", map[string]string{"Forwarded": `for=198.51.100.7;proto="ws<script>"`}, "198.51.100.7", "http"},
+		{"real ip proto invalid", "10.0.0.1:80", map[string]string{"X-Real-IP": "1.2.3.4", "X-Forwarded-Proto": "javascript"}, "1.2.3.4", "http"},
+		{"real ip", "10.0.0.1:80", map[string]string{"X-Real-IP": "1.2.3.4"}, "1.2.3.4", "http"},
+		{"ipv6 peer", "[2001:db8::1]:80", map[string]string{"X-Forwarded-For": "1.2.3.4"}, "1.2.3.4", "http"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remote
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			ci := r.clientInfo(req)
+			if ci.IP != tc.wantIP || ci.Scheme != tc.wantScheme {
+				t.Errorf("got %s/%s, want %s/%s", ci.IP, ci.Scheme, tc.wantIP, tc.wantScheme)
+			}
+		})
+	}
+}
+
+func TestClientInfoTLS(t *testing.T) {
+	r, _ := New(nil)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if ci := r.clientInfo(req); ci.IP != "192.0.2.1" || ci.Scheme != "https" {
+		t.Errorf("got %+v, want the remote address with https", ci)
+	}
+}
+
+func TestWithTrustedProxiesInvalid(t *testing.T) {
+	_, err := New(nil, WithTrustedProxies("not-an-ip"))
+	if !errors.Is(err, ErrorInvalidProxy) {
+		t.Errorf("err = %v, want %v", err, ErrorInvalidProxy)
+	}
+}
+
+func handlerClientInfo(ctx context.Context, ci ClientInfo, ptr *ClientInfo) string {
+	return strings.Join([]string{ClientIPFromCtx(ctx), ci.IP, ptr.Scheme}, " ")
+}
+
+func TestClientInfoArg(t *testing.T) {
+	h := buildTestHandlerWithOpts(t, []Route{
+		{Name: "client", Method: "GET", Path: "/client", Handler: handlerClientInfo},
+	}, WithTrustedProxies("192.0.2.0/24"))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/client", nil)
+	req.Header.Set("Accept", "text/plain")
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "1.2.3.4 1.2.3.4 https" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+}
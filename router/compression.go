@@ -0,0 +1,221 @@
+package router
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ninlil/butler/bufferedresponse"
+	"github.com/ninlil/butler/log"
+)
+
+const (
+	hdrAcceptEncoding  = "Accept-Encoding"
+	hdrContentEncoding = "Content-Encoding"
+)
+
+// Encoder compresses responses with a content-coding, implement it to add more algorithms (ex "br" or "zstd")
+type Encoder interface {
+	// Encoding is the name of the content-coding, as used in 'Accept-Encoding' and 'Content-Encoding'
+	Encoding() string
+
+	// NewWriter returns a writer compressing to w, level is as set in WithCompression (or -1 for the default)
+	NewWriter(w io.Writer, level int) (io.WriteCloser, error)
+}
+
+// GzipEncoder is the built-in "gzip" encoder
+type GzipEncoder struct{}
+
+// Encoding returns "gzip"
+func (GzipEncoder) Encoding() string { return "gzip" }
+
+// NewWriter returns a gzip-writer
+func (GzipEncoder) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, level)
+}
+
+// DeflateEncoder is the built-in "deflate" encoder (the zlib-format, as specified for http)
+type DeflateEncoder struct{}
+
+// Encoding returns "deflate"
+func (DeflateEncoder) Encoding() string { return "deflate" }
+
+// NewWriter returns a zlib-writer
+func (DeflateEncoder) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, level)
+}
+
+// compressedTypes are the media-types compressed, other than "text/*" and "+json" or "+xml" suffixes
+var compressedTypes = map[string]bool{
+	"application/json":       true,
+	"application/xml":        true,
+	"application/javascript": true,
+	"application/yaml":       true,
+	"application/x-yaml":     true,
+	"application/x-ndjson":   true,
+	"image/svg+xml":          true,
+}
+
+type compression struct {
+	minSize  int
+	levels   map[string]int
+	encoders []Encoder // in order of preference, when the client doesn't prefer any
+}
+
+// WithCompression compresses responses of at least minSize bytes, when the client accepts it.
+// Levels are per encoding (ex {"gzip": gzip.BestSpeed}), the default level is used for encodings not listed.
+//
+// Text, json, xml and similar content is compressed, while content that already has a
+// 'Content-Encoding' is left as is. Use WithEncoder to add more encodings.
+func WithCompression(minSize int, levels map[string]int) Option {
+	return func(r *Router) error {
+		if minSize < 0 {
+			return ErrorInvalidCompression
+		}
+		c := r.compressionConfig()
+		c.minSize = minSize
+		for encoding, level := range levels {
+			c.levels[strings.ToLower(encoding)] = level
+		}
+		for _, enc := range c.encoders {
+			if level, found := c.levels[strings.ToLower(enc.Encoding())]; found {
+				if _, err := enc.NewWriter(io.Discard, level); err != nil {
+					return ErrorInvalidCompression
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// WithEncoder adds an encoder for compression, preferred over the built-in encoders (or replaces
+// one with the same encoding). It enables compression (see WithCompression) if not already enabled.
+func WithEncoder(enc Encoder) Option {
+	return func(r *Router) error {
+		c := r.compressionConfig()
+		encoders := []Encoder{enc}
+		for _, existing := range c.encoders {
+			if !strings.EqualFold(existing.Encoding(), enc.Encoding()) {
+				encoders = append(encoders, existing)
+			}
+		}
+		c.encoders = encoders
+		return nil
+	}
+}
+
+func (r *Router) compressionConfig() *compression {
+	if r.compression == nil {
+		r.compression = &compression{
+			levels:   make(map[string]int),
+			encoders: []Encoder{GzipEncoder{}, DeflateEncoder{}},
+		}
+	}
+	return r.compression
+}
+
+// handler compresses the buffered response, when the handler is done
+func (c *compression) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		w2, ok := bufferedresponse.Get(w)
+		if !ok || !c.eligible(w2, r) {
+			return
+		}
+		h := w.Header()
+		h.Add("Vary", hdrAcceptEncoding)
+		if w2.Size() < c.minSize {
+			return
+		}
+		enc := c.negotiate(r.Header.Get(hdrAcceptEncoding))
+		if enc == nil {
+			return
+		}
+
+		level, found := c.levels[strings.ToLower(enc.Encoding())]
+		if !found {
+			level = -1
+		}
+		var buf bytes.Buffer
+		zw, err := enc.NewWriter(&buf, level)
+		if err == nil {
+			_, err = zw.Write(w2.Bytes())
+			if err2 := zw.Close(); err == nil {
+				err = err2
+			}
+		}
+		if err != nil {
+			log.FromCtx(r.Context()).Warn().Msgf("router: unable to compress using %s: %v", enc.Encoding(), err)
+			return
+		}
+		if buf.Len() >= w2.Size() {
+			return
+		}
+
+		w2.Reset()
+		_, _ = w2.Write(buf.Bytes())
+		h.Set(hdrContentEncoding, enc.Encoding())
+		if h.Get("Content-Length") != "" {
+			w2.SetContentLength()
+		}
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+	})
+}
+
+// eligible checks if the response is of a type that can be compressed
+func (c *compression) eligible(w2 *bufferedresponse.ResponseWriter, r *http.Request) bool {
+	status := w2.Status()
+	if r.Method == http.MethodHead || status < 200 || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	h := w2.Header()
+	if h.Get(hdrContentEncoding) != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || compressedTypes[mediaType] ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// negotiate selects the encoder with the highest q-value in the 'Accept-Encoding' header,
+// or nil if none is acceptable
+func (c *compression) negotiate(accept string) Encoder {
+	if accept == "" {
+		return nil
+	}
+	qvalues := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				q = f
+			}
+		}
+		qvalues[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	var best Encoder
+	var bestQ float64
+	for _, enc := range c.encoders {
+		q, found := qvalues[strings.ToLower(enc.Encoding())]
+		if !found {
+			q = qvalues["*"]
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
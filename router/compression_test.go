@@ -0,0 +1,146 @@
+package router
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var largeText = strings.Repeat("butler ", 200)
+
+func handlerLargeText() string { return largeText }
+
+func handlerPreEncoded(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Content-Encoding", "gzip")
+	_, _ = w.Write([]byte(largeText))
+}
+
+func handlerImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write([]byte(largeText))
+}
+
+// upperEncoder is a test-encoder, "compressing" to the first word in upper-case
+type upperEncoder struct{}
+
+func (upperEncoder) Encoding() string { return "x-upper" }
+
+func (upperEncoder) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return nopCloser{w}, nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nc nopCloser) Write(p []byte) (int, error) {
+	_, err := nc.Writer.Write([]byte(strings.ToUpper(string(p[:7]))))
+	return len(p), err
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestCompression(t *testing.T) {
+	routes := []Route{
+		{Name: "text", Method: "GET", Path: "/text", Handler: handlerLargeText},
+		{Name: "small", Method: "GET", Path: "/small/{name}", Handler: handlerName},
+		{Name: "encoded", Method: "GET", Path: "/encoded", Handler: handlerPreEncoded},
+		{Name: "image", Method: "GET", Path: "/image", Handler: handlerImage},
+	}
+	h := buildTestHandlerWithOpts(t, routes, WithCompression(100, map[string]int{"gzip": gzip.BestSpeed}))
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", "text/plain")
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		w := serve("/text", "deflate;q=0.5, gzip")
+		if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("headers = %v", w.Header())
+		}
+		if w.Header().Get("Content-Length") != strconv.Itoa(w.Body.Len()) {
+			t.Errorf("Content-Length = %s, body is %d bytes", w.Header().Get("Content-Length"), w.Body.Len())
+		}
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := io.ReadAll(zr); string(body) != largeText {
+			t.Errorf("decompressed body differs")
+		}
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		w := serve("/text", "deflate, gzip;q=0.5")
+		if w.Header().Get("Content-Encoding") != "deflate" {
+			t.Fatalf("headers = %v", w.Header())
+		}
+		zr, err := zlib.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := io.ReadAll(zr); string(body) != largeText {
+			t.Errorf("decompressed body differs")
+		}
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		vary   bool
+	}{
+		{"not accepted", "/text", "", true},
+		{"refused", "/text", "gzip;q=0, *;q=0", true},
+		{"below min-size", "/small/bob", "gzip", true},
+		{"already encoded", "/encoded", "gzip", false},
+		{"not compressible", "/image", "gzip", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(tc.path, tc.accept)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d", w.Code)
+			}
+			if enc := w.Header().Get("Content-Encoding"); tc.path != "/encoded" && enc != "" {
+				t.Errorf("Content-Encoding = %q, want none", enc)
+			}
+			if vary := w.Header().Get("Vary") != ""; vary != tc.vary {
+				t.Errorf("Vary = %q", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestWithEncoder(t *testing.T) {
+	h := buildTestHandlerWithOpts(t,
+		[]Route{{Name: "text", Method: "GET", Path: "/text", Handler: handlerLargeText}},
+		WithEncoder(upperEncoder{}))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/text", nil)
+	req.Header.Set("Accept", "text/plain")
+	req.Header.Set("Accept-Encoding", "gzip, x-upper")
+	h.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "x-upper" || w.Body.String() != "BUTLER " {
+		t.Errorf("headers = %v, body = %q", w.Header(), w.Body.String())
+	}
+}
+
+func TestWithCompressionInvalid(t *testing.T) {
+	if _, err := New(nil, WithCompression(0, map[string]int{"gzip": 42})); !errors.Is(err, ErrorInvalidCompression) {
+		t.Errorf("err = %v, want %v", err, ErrorInvalidCompression)
+	}
+}
@@ -0,0 +1,247 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NotReadyOverloaded is the prefix of the readiness-reasons used while requests are shed, see WithConcurrency.
+// The reason is "overloaded:<router>" for the router-wide limit and "overloaded:<router>:<route>" for the limit of a route.
+const NotReadyOverloaded = "overloaded"
+
+// ConcurrencyConfig limits the requests handled at the same time by a router (see WithConcurrency) or a single route
+type ConcurrencyConfig struct {
+	// MaxInFlight is the number of requests handled at the same time
+	MaxInFlight int
+
+	// QueueSize is the number of requests waiting for their turn (default MaxInFlight), more are rejected at once
+	QueueSize int
+
+	// MaxWait is the longest time a request waits in the queue (default 1 second)
+	MaxWait time.Duration
+
+	// TargetWait enables adaptive shedding: when the waiting-time stays above TargetWait for
+	// longer than MaxWait, requests are rejected instead of queued until the queue has drained
+	TargetWait time.Duration
+
+	// RetryAfter is the value of the 'Retry-After' header of rejected requests (default 1 second)
+	RetryAfter time.Duration
+
+	// Disabled excludes a single route from the router-wide limit
+	Disabled bool
+}
+
+// WithMaxInFlight limits the number of requests handled at the same time by the router,
+// see WithConcurrency for the queue and load shedding
+func WithMaxInFlight(n int) Option {
+	return WithConcurrency(ConcurrencyConfig{MaxInFlight: n})
+}
+
+// WithConcurrency limits the number of requests handled at the same time by the router.
+// Requests over the limit wait in a queue, and when that is full (or MaxWait passes) they are rejected
+// with '503 Service Unavailable' and the readiness-probe fails until the queue has drained.
+//
+// Use Route.Concurrency for a limit on a single route, in addition to the router-wide limit.
+func WithConcurrency(config ConcurrencyConfig) Option {
+	return func(r *Router) error {
+		limiter, err := newConcurrencyLimiter(&config, "", "") // the reason is set by New, once the router is named
+		if err != nil {
+			return err
+		}
+		r.concurrency = limiter
+		return nil
+	}
+}
+
+// concurrencyLimiter is a ConcurrencyConfig with its defaults applied
+type concurrencyLimiter struct {
+	name       string // of the route, empty for the router
+	reason     string // used with SetNotReady when overloaded
+	max        int
+	queueSize  int
+	maxWait    time.Duration
+	targetWait time.Duration
+	retryAfter time.Duration
+
+	slots      chan struct{}
+	waiting    atomic.Int64
+	overloaded atomic.Bool
+	retired    atomic.Bool // the route has been removed or replaced
+
+	mutex     sync.Mutex
+	slowSince time.Time // when the waiting-time went above targetWait
+}
+
+func newConcurrencyLimiter(cfg *ConcurrencyConfig, name, reason string) (*concurrencyLimiter, error) {
+	if cfg == nil || cfg.Disabled {
+		return nil, nil
+	}
+	if cfg.MaxInFlight <= 0 || cfg.QueueSize < 0 || cfg.MaxWait < 0 || cfg.TargetWait < 0 || cfg.RetryAfter < 0 {
+		return nil, ErrorInvalidConcurrency
+	}
+	cl := &concurrencyLimiter{
+		name:       name,
+		reason:     reason,
+		max:        cfg.MaxInFlight,
+		queueSize:  cfg.QueueSize,
+		maxWait:    cfg.MaxWait,
+		targetWait: cfg.TargetWait,
+		retryAfter: cfg.RetryAfter,
+		slots:      make(chan struct{}, cfg.MaxInFlight),
+	}
+	if cl.queueSize == 0 {
+		cl.queueSize = cl.max
+	}
+	if cl.maxWait == 0 {
+		cl.maxWait = time.Second
+	}
+	if cl.retryAfter == 0 {
+		cl.retryAfter = time.Second
+	}
+	return cl, nil
+}
+
+// initConcurrency sets up the limiter of the route, when it has a configuration of its own
+func (rt *Route) initConcurrency() (err error) {
+	if rt.Concurrency != nil {
+		rt.concurrency, err = newConcurrencyLimiter(rt.Concurrency, rt.id(), rt.router.overloadedReason(rt))
+	}
+	return err
+}
+
+// overloadedReason is the readiness-reason of the limiter of the route, or of the router when rt is nil
+func (r *Router) overloadedReason(rt *Route) string {
+	reason := NotReadyOverloaded + ":" + r.name
+	if rt != nil {
+		reason += ":" + rt.id()
+	}
+	return reason
+}
+
+// concurrencyLimiters returns the active limiters for the route, router-wide first
+func (rt *Route) concurrencyLimiters() []*concurrencyLimiter {
+	var list []*concurrencyLimiter
+	if rt.router != nil && rt.router.concurrency != nil && (rt.Concurrency == nil || !rt.Concurrency.Disabled) {
+		list = append(list, rt.router.concurrency)
+	}
+	if rt.concurrency != nil {
+		list = append(list, rt.concurrency)
+	}
+	return list
+}
+
+// acquire waits for a free slot, false means the request should be rejected
+func (cl *concurrencyLimiter) acquire(r *http.Request) bool {
+	select {
+	case cl.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if cl.shedding() {
+		cl.setOverloaded()
+		return false
+	}
+	if cl.waiting.Add(1) > int64(cl.queueSize) {
+		cl.waiting.Add(-1)
+		cl.setOverloaded()
+		return false
+	}
+	defer cl.waiting.Add(-1)
+
+	start := time.Now()
+	timer := time.NewTimer(cl.maxWait)
+	defer timer.Stop()
+
+	select {
+	case cl.slots <- struct{}{}:
+		cl.observe(time.Since(start))
+		return true
+	case <-timer.C:
+		cl.observe(time.Since(start))
+		cl.setOverloaded()
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// release frees the slot, and clears the overload when the queue is empty
+func (cl *concurrencyLimiter) release() {
+	<-cl.slots
+	if cl.waiting.Load() == 0 && cl.overloaded.CompareAndSwap(true, false) {
+		cl.mutex.Lock()
+		cl.slowSince = time.Time{}
+		cl.mutex.Unlock()
+		ClearNotReady(cl.reason)
+	}
+}
+
+// observe records the waiting-time of a request for the adaptive shedding
+func (cl *concurrencyLimiter) observe(wait time.Duration) {
+	if cl.targetWait == 0 {
+		return
+	}
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	switch {
+	case wait <= cl.targetWait:
+		cl.slowSince = time.Time{}
+	case cl.slowSince.IsZero():
+		cl.slowSince = time.Now()
+	}
+}
+
+// shedding checks if the waiting-time has been too long for too long
+func (cl *concurrencyLimiter) shedding() bool {
+	if cl.targetWait == 0 {
+		return false
+	}
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+
+	return !cl.slowSince.IsZero() && time.Since(cl.slowSince) > cl.maxWait
+}
+
+func (cl *concurrencyLimiter) setOverloaded() {
+	if !cl.retired.Load() && cl.overloaded.CompareAndSwap(false, true) {
+		SetNotReady(cl.reason)
+	}
+}
+
+// retire clears the overload of a limiter no longer in use, requests still being handled
+// by it can't mark the service as not ready again
+func (cl *concurrencyLimiter) retire() {
+	cl.retired.Store(true)
+	if cl.overloaded.CompareAndSwap(true, false) {
+		ClearNotReady(cl.reason)
+	}
+}
+
+// report is the line of the limiter in the readiness-probe
+func (cl *concurrencyLimiter) report() string {
+	what := "in-flight"
+	if cl.name != "" {
+		what += "[" + cl.name + "]"
+	}
+	return fmt.Sprintf("%s %d/%d, queued %d/%d", what, len(cl.slots), cl.max, cl.waiting.Load(), cl.queueSize)
+}
+
+// handler is the middleware limiting the concurrent requests of a route
+func (cl *concurrencyLimiter) handler(rt *Route) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cl.acquire(r) {
+				w.Header().Set(hdrRetryAfter, seconds(cl.retryAfter))
+				rt.writeError(ErrOverloaded, w, r, http.StatusServiceUnavailable)
+				return
+			}
+			defer cl.release()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingRoute returns a route whose requests are held until release is closed
+func blockingRoute(name string, started chan<- struct{}, release <-chan struct{}) Route {
+	return Route{Name: name, Method: "GET", Path: "/" + name, Handler: func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}}
+}
+
+func TestConcurrencyQueue(t *testing.T) {
+	t.Cleanup(func() { ClearNotReady(NotReadyOverloaded + ":default") })
+
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	h := buildTestHandlerWithOpts(t, []Route{blockingRoute("slow", started, release)},
+		WithConcurrency(ConcurrencyConfig{MaxInFlight: 1, QueueSize: 1, MaxWait: time.Minute, RetryAfter: 5 * time.Second}))
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+		return w
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = serve().Code
+		}()
+		if i == 0 {
+			<-started
+		}
+	}
+	waitFor(t, func() bool { return strings.Contains(probe(h).Body.String(), "queued 1/1") })
+
+	// the queue is full
+	w := serve()
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "5" {
+		t.Errorf("third request: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := probe(h); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "overloaded:default\nin-flight 1/1, queued 1/1") {
+		t.Errorf("probe while overloaded: %d %q", w.Code, w.Body.String())
+	}
+
+	close(release)
+	wg.Wait()
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK {
+		t.Errorf("queued requests: %v, want both 200", codes)
+	}
+	if w := probe(h); w.Code != http.StatusOK || w.Body.String() != "in-flight 0/1, queued 0/1" {
+		t.Errorf("probe after drain: %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestConcurrencyMaxWait(t *testing.T) {
+	t.Cleanup(func() { ClearNotReady(NotReadyOverloaded + ":default:slow") })
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	h := buildTestHandlerWithOpts(t, []Route{
+		func() Route {
+			rt := blockingRoute("slow", started, release)
+			rt.Concurrency = &ConcurrencyConfig{MaxInFlight: 1, MaxWait: 20 * time.Millisecond}
+			return rt
+		}(),
+	})
+
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+	<-started
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != `{"error":"service overloaded"}` {
+		t.Errorf("status %d, body %q", w.Code, w.Body.String())
+	}
+	if !strings.Contains(probe(h).Body.String(), "in-flight[slow] 1/1") {
+		t.Errorf("probe = %q, want the load of the route", probe(h).Body.String())
+	}
+}
+
+func TestConcurrencyRemovedRoute(t *testing.T) {
+	reason := NotReadyOverloaded + ":default:removed"
+	t.Cleanup(func() { ClearNotReady(reason) })
+
+	route := Route{Name: "removed", Method: "GET", Path: "/removed", Handler: handlerReturnStatus,
+		Concurrency: &ConcurrencyConfig{MaxInFlight: 1}}
+	r, err := New([]Route{route})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	r.Handler()
+
+	for _, replace := range []bool{true, false} {
+		limiter := r.routes[0].concurrency
+		limiter.setOverloaded()
+		if !slices.Contains(NotReadyReasons(), reason) {
+			t.Fatalf("reasons = %v, want %q", NotReadyReasons(), reason)
+		}
+		if replace {
+			err = r.Replace([]Route{route})
+		} else {
+			err = r.Remove("removed")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.Contains(NotReadyReasons(), reason) {
+			t.Errorf("replace=%v: reasons = %v, want %q cleared", replace, NotReadyReasons(), reason)
+		}
+		limiter.setOverloaded()
+		if slices.Contains(NotReadyReasons(), reason) {
+			t.Errorf("replace=%v: a retired limiter set %q", replace, reason)
+		}
+	}
+}
+
+func TestConcurrencyReasons(t *testing.T) {
+	limit := &ConcurrencyConfig{MaxInFlight: 1}
+	routes := []Route{
+		{Method: "GET", Path: "/x", Handler: handlerReturnStatus, Concurrency: limit},
+		{Method: "POST", Path: "/x", Handler: handlerReturnStatus, Concurrency: limit},
+	}
+	seen := make(map[string]bool)
+	for _, name := range []string{"a", "b"} {
+		r, err := New(routes, WithName(name), WithMaxInFlight(1))
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		for _, cl := range []*concurrencyLimiter{r.concurrency, r.routes[0].concurrency, r.routes[1].concurrency} {
+			if seen[cl.reason] {
+				t.Errorf("router %s: reason %q is not unique", name, cl.reason)
+			}
+			seen[cl.reason] = true
+		}
+	}
+	if !seen["overloaded:a"] || !seen["overloaded:b:POST /x"] {
+		t.Errorf("reasons = %v", seen)
+	}
+}
+
+func TestConcurrencyDisabledRoute(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	h := buildTestHandlerWithOpts(t, []Route{
+		blockingRoute("slow", started, release),
+		{Name: "free", Method: "GET", Path: "/free", Handler: handlerReturnStruct, Concurrency: &ConcurrencyConfig{Disabled: true}},
+	}, WithMaxInFlight(1))
+
+	go h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+	<-started
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/free", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status %d, want 200", w.Code)
+	}
+}
+
+func TestConcurrencyShedding(t *testing.T) {
+	t.Cleanup(func() { ClearNotReady(NotReadyOverloaded) })
+
+	cl, _ := newConcurrencyLimiter(&ConcurrencyConfig{MaxInFlight: 1, MaxWait: time.Minute, TargetWait: time.Millisecond}, "", NotReadyOverloaded)
+	req := httptest.NewRequest("GET", "/", nil)
+	if !cl.acquire(req) {
+		t.Fatal("first acquire failed")
+	}
+
+	// the waiting-time has been above the target for longer than MaxWait
+	cl.observe(time.Second)
+	cl.slowSince = cl.slowSince.Add(-2 * time.Minute)
+
+	start := time.Now()
+	if cl.acquire(req) {
+		t.Fatal("acquire succeeded while shedding")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("the request was queued instead of shed")
+	}
+
+	cl.release()
+	if cl.shedding() || !IsReady() {
+		t.Error("still shedding after the queue drained")
+	}
+}
+
+func TestWithConcurrencyInvalid(t *testing.T) {
+	if _, err := New(nil, WithMaxInFlight(0)); !errors.Is(err, ErrorInvalidConcurrency) {
+		t.Errorf("err = %v, want %v", err, ErrorInvalidConcurrency)
+	}
+}
+
+func probe(h http.Handler) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	return w
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal("timeout waiting for condition")
+}
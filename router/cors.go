@@ -0,0 +1,169 @@
+package router
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	hdrOrigin         = "Origin"
+	hdrRequestMethod  = "Access-Control-Request-Method"
+	hdrRequestHeaders = "Access-Control-Request-Headers"
+	hdrAllowOrigin    = "Access-Control-Allow-Origin"
+	hdrAllowMethods   = "Access-Control-Allow-Methods"
+	hdrAllowHeaders   = "Access-Control-Allow-Headers"
+	hdrAllowCreds     = "Access-Control-Allow-Credentials"
+	hdrExposeHeaders  = "Access-Control-Expose-Headers"
+	hdrMaxAge         = "Access-Control-Max-Age"
+)
+
+// CORSConfig is the Cross-Origin Resource Sharing configuration for a router (see WithCORS) or a single route
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to make requests, "*" allows any origin (but not with AllowCredentials)
+	// and a single "*" within an origin acts as a wildcard (ex "https://*.example.com")
+	AllowedOrigins []string
+
+	// AllowedMethods defaults to the methods routed for the requested path
+	AllowedMethods []string
+
+	// AllowedHeaders defaults to the headers requested by the client
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers the client is allowed to read
+	ExposedHeaders []string
+
+	// AllowCredentials allows cookies and authorization headers to be sent
+	AllowCredentials bool
+
+	// MaxAge is how long the client may cache the preflight response
+	MaxAge time.Duration
+
+	// Disabled turns CORS off, used to exclude a single route from the router-wide configuration
+	Disabled bool
+}
+
+// WithCORS enables CORS-handling for all routes, use Route.CORS to override for a single route
+func WithCORS(config CORSConfig) Option {
+	return func(r *Router) error {
+		if err := config.validate(); err != nil {
+			return err
+		}
+		r.cors = &config
+		return nil
+	}
+}
+
+// validate rejects any origin combined with credentials, as browsers do
+func (cors *CORSConfig) validate() error {
+	if cors != nil && cors.AllowCredentials && slices.Contains(cors.AllowedOrigins, "*") {
+		return ErrorInvalidCORS
+	}
+	return nil
+}
+
+// corsConfig returns the active CORS-configuration for the route, or nil if none
+func (rt *Route) corsConfig() *CORSConfig {
+	cors := rt.CORS
+	if cors == nil && rt.router != nil {
+		cors = rt.router.cors
+	}
+	if cors == nil || cors.Disabled {
+		return nil
+	}
+	return cors
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get(hdrOrigin) != "" && r.Header.Get(hdrRequestMethod) != ""
+}
+
+// handler is the middleware adding CORS-headers to the response of a route
+func (cors *CORSConfig) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cors.writeHeaders(w, r)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowOrigin returns the value for the 'Access-Control-Allow-Origin' header, if the origin is allowed
+func (cors *CORSConfig) allowOrigin(origin string) (string, bool) {
+	for _, allowed := range cors.AllowedOrigins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if matchOrigin(allowed, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+func matchOrigin(pattern, origin string) bool {
+	if strings.EqualFold(pattern, origin) {
+		return true
+	}
+	before, after, found := strings.Cut(pattern, "*")
+	if !found {
+		return false
+	}
+	return len(origin) > len(before)+len(after) &&
+		strings.HasPrefix(strings.ToLower(origin), strings.ToLower(before)) &&
+		strings.HasSuffix(strings.ToLower(origin), strings.ToLower(after))
+}
+
+// writeHeaders writes the CORS-headers for a regular (non-preflight) request
+func (cors *CORSConfig) writeHeaders(w http.ResponseWriter, r *http.Request) bool {
+	if cors == nil || cors.Disabled {
+		return false
+	}
+	origin := r.Header.Get(hdrOrigin)
+	if origin == "" {
+		return false
+	}
+
+	h := w.Header()
+	h.Add("Vary", hdrOrigin)
+
+	value, ok := cors.allowOrigin(origin)
+	if !ok {
+		return false
+	}
+	h.Set(hdrAllowOrigin, value)
+	if cors.AllowCredentials {
+		h.Set(hdrAllowCreds, "true")
+	}
+	if len(cors.ExposedHeaders) > 0 {
+		h.Set(hdrExposeHeaders, strings.Join(cors.ExposedHeaders, ", "))
+	}
+	return true
+}
+
+// writePreflight writes the CORS-headers for a preflight request
+func (cors *CORSConfig) writePreflight(w http.ResponseWriter, r *http.Request, allowed []string) {
+	if !cors.writeHeaders(w, r) {
+		return
+	}
+
+	h := w.Header()
+	h.Add("Vary", hdrRequestMethod)
+	h.Add("Vary", hdrRequestHeaders)
+
+	methods := cors.AllowedMethods
+	if len(methods) == 0 {
+		methods = allowed
+	}
+	h.Set(hdrAllowMethods, strings.Join(methods, ", "))
+
+	if len(cors.AllowedHeaders) > 0 {
+		h.Set(hdrAllowHeaders, strings.Join(cors.AllowedHeaders, ", "))
+	} else if requested := r.Header.Get(hdrRequestHeaders); requested != "" {
+		h.Set(hdrAllowHeaders, requested)
+	}
+
+	if cors.MaxAge > 0 {
+		h.Set(hdrMaxAge, strconv.Itoa(int(cors.MaxAge.Seconds())))
+	}
+}
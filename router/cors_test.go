@@ -0,0 +1,119 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{"https://example.com", "https://example.com", true},
+		{"https://example.com", "https://EXAMPLE.com", true},
+		{"https://example.com", "https://other.com", false},
+		{"https://*.example.com", "https://api.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "http://api.example.com", false},
+	}
+	for _, tc := range tests {
+		if got := matchOrigin(tc.pattern, tc.origin); got != tc.want {
+			t.Errorf("matchOrigin(%q, %q) = %t, want %t", tc.pattern, tc.origin, got, tc.want)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	routes := []Route{
+		{Name: "get", Method: "GET", Path: "/items", Handler: handlerReturnStruct},
+		{Name: "put", Method: "PUT", Path: "/items", Handler: handlerReturnStatus},
+		{Name: "private", Method: "GET", Path: "/private", Handler: handlerReturnStruct,
+			CORS: &CORSConfig{Disabled: true}},
+	}
+	h := buildTestHandlerWithOpts(t, routes, WithCORS(CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+		ExposedHeaders: []string{"X-Request-Id"},
+		MaxAge:         10 * time.Minute,
+	}))
+
+	t.Run("preflight", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("OPTIONS", "/items", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "PUT")
+		req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+		h.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+		want := map[string]string{
+			"Access-Control-Allow-Origin":  "https://app.example.com",
+			"Access-Control-Allow-Methods": "GET, HEAD, OPTIONS, PUT",
+			"Access-Control-Allow-Headers": "Content-Type",
+			"Access-Control-Max-Age":       "600",
+		}
+		for key, value := range want {
+			if got := w.Header().Get(key); got != value {
+				t.Errorf("%s = %q, want %q", key, got, value)
+			}
+		}
+	})
+
+	t.Run("actual request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/items", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		h.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("Access-Control-Allow-Origin = %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-Id" {
+			t.Errorf("Access-Control-Expose-Headers = %q", got)
+		}
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/items", nil)
+		req.Header.Set("Origin", "https://evil.com")
+		h.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+		}
+	})
+
+	t.Run("route override", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("OPTIONS", "/private", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		h.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+		}
+	})
+}
+
+func TestCORSAnyOriginWithCredentials(t *testing.T) {
+	config := CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	if _, err := New(nil, WithCORS(config)); err != ErrorInvalidCORS {
+		t.Errorf("WithCORS: err = %v, want %v", err, ErrorInvalidCORS)
+	}
+	rt := Route{Name: "r", Method: "GET", Path: "/r", Handler: handlerReturnStruct, CORS: &config}
+	if _, err := new(Router).newRoute(rt); err != ErrorInvalidCORS {
+		t.Errorf("Route.CORS: err = %v, want %v", err, ErrorInvalidCORS)
+	}
+
+	config.AllowedOrigins = []string{"https://*.example.com"}
+	if _, err := New(nil, WithCORS(config)); err != nil {
+		t.Errorf("a wildcard origin with credentials: %v", err)
+	}
+}
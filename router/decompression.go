@@ -0,0 +1,137 @@
+package router
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultMaxDecompressed is the default limit of a decompressed request-body, see WithMaxDecompressedSize
+const defaultMaxDecompressed = 10 << 20
+
+// supportedEncodings are the content-codings of request-bodies decoded by the router
+var supportedEncodings = []string{"gzip", "deflate"}
+
+// WithMaxDecompressedSize limits the size of a decompressed request-body (default 10 MiB),
+// larger bodies are rejected with '413 Payload Too Large'
+func WithMaxDecompressedSize(n int64) Option {
+	return func(r *Router) error {
+		if n <= 0 {
+			return ErrorInvalidCompression
+		}
+		r.maxDecompressed = n
+		return nil
+	}
+}
+
+// WithoutDecompression turns off the decoding of request-bodies, the handlers get the body as sent
+func WithoutDecompression() Option {
+	return func(r *Router) error {
+		r.maxDecompressed = -1
+		return nil
+	}
+}
+
+// limitedBody is the decoded request-body, failing when the limit is exceeded
+type limitedBody struct {
+	r      io.Reader
+	closer io.Closer
+	left   int64
+	limit  int64
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	if lb.left <= 0 {
+		// probe for more data, to tell an exact fit from an overflow
+		var one [1]byte
+		if n, _ := lb.r.Read(one[:]); n > 0 {
+			return 0, fmt.Errorf("%w, the limit is %d bytes", ErrBodyTooLarge, lb.limit)
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > lb.left {
+		p = p[:lb.left]
+	}
+	n, err := lb.r.Read(p)
+	lb.left -= int64(n)
+	return n, err
+}
+
+func (lb *limitedBody) Close() error {
+	return lb.closer.Close()
+}
+
+// decompressHandler decodes request-bodies with a 'Content-Encoding', for the route
+func (r *Router) decompressHandler(rt *Route) func(http.Handler) http.Handler {
+	limit := r.maxDecompressed
+	if limit == 0 {
+		limit = defaultMaxDecompressed
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			encodings := contentEncodings(req.Header)
+			if len(encodings) == 0 || req.Body == nil || req.Body == http.NoBody {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			var body io.Reader = req.Body
+			// the encodings are listed in the order they were applied
+			for i := len(encodings) - 1; i >= 0; i-- {
+				var err error
+				switch encodings[i] {
+				case "gzip", "x-gzip":
+					body, err = gzip.NewReader(body)
+				case "deflate":
+					body, err = newDeflateReader(body)
+				default:
+					w.Header().Set(hdrAcceptEncoding, strings.Join(supportedEncodings, ", "))
+					rt.writeError(fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encodings[i]), w, req, http.StatusUnsupportedMediaType)
+					return
+				}
+				if err != nil {
+					rt.writeError(fmt.Errorf("invalid %s-body: %w", encodings[i], err), w, req, http.StatusBadRequest)
+					return
+				}
+			}
+
+			req.Body = &limitedBody{r: body, closer: req.Body, left: limit, limit: limit}
+			req.Header.Del(hdrContentEncoding)
+			req.Header.Del("Content-Length")
+			req.ContentLength = -1
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// contentEncodings returns the content-codings of the request, without "identity"
+func contentEncodings(h http.Header) []string {
+	var list []string
+	for _, value := range h.Values(hdrContentEncoding) {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding != "" && encoding != "identity" {
+				list = append(list, encoding)
+			}
+		}
+	}
+	return list
+}
+
+// newDeflateReader reads "deflate" as specified (zlib), or as raw deflate which some clients send
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,97 @@
+package router
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func compress(t *testing.T, encoding, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var zw io.WriteCloser
+	switch encoding {
+	case "gzip":
+		zw = gzip.NewWriter(&buf)
+	case "deflate":
+		zw = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		zw, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	_, _ = zw.Write([]byte(body))
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+func TestDecompression(t *testing.T) {
+	const body = `{"name":"apple","price":1.5}`
+	routes := []Route{{Name: "add", Method: "POST", Path: "/items", Handler: handlerBody}}
+
+	tests := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		opts       []Option
+		wantStatus int
+	}{
+		{"gzip", "gzip", compress(t, "gzip", body), nil, http.StatusOK},
+		{"deflate", "deflate", compress(t, "deflate", body), nil, http.StatusOK},
+		{"raw deflate", "deflate", compress(t, "raw-deflate", body), nil, http.StatusOK},
+		{"identity", "identity", []byte(body), nil, http.StatusOK},
+		{"unsupported", "br", []byte(body), nil, http.StatusUnsupportedMediaType},
+		{"invalid gzip", "gzip", []byte(body), nil, http.StatusBadRequest},
+		{"too large", "gzip", compress(t, "gzip", body), []Option{WithMaxDecompressedSize(10)}, http.StatusRequestEntityTooLarge},
+		{"exact fit", "gzip", compress(t, "gzip", body), []Option{WithMaxDecompressedSize(int64(len(body)))}, http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := buildTestHandlerWithOpts(t, routes, tc.opts...)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/items", bytes.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", tc.encoding)
+			h.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.wantStatus, w.Body.String())
+			}
+			if w.Code == http.StatusOK && !strings.Contains(w.Body.String(), `"apple"`) {
+				t.Errorf("body = %q", w.Body.String())
+			}
+			if w.Code == http.StatusUnsupportedMediaType && w.Header().Get("Accept-Encoding") != "gzip, deflate" {
+				t.Errorf("Accept-Encoding = %q", w.Header().Get("Accept-Encoding"))
+			}
+		})
+	}
+}
+
+func TestWithoutDecompression(t *testing.T) {
+	var got []byte
+	raw := func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+	}
+	h := buildTestHandlerWithOpts(t, []Route{{Name: "raw", Method: "POST", Path: "/raw", Handler: raw}}, WithoutDecompression())
+
+	sent := compress(t, "gzip", "hello")
+	req := httptest.NewRequest("POST", "/raw", bytes.NewReader(sent))
+	req.Header.Set("Content-Encoding", "gzip")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !bytes.Equal(got, sent) {
+		t.Errorf("the body was decoded")
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	lb := &limitedBody{r: strings.NewReader("0123456789"), closer: io.NopCloser(nil), left: 5, limit: 5}
+	buf, err := io.ReadAll(lb)
+	if string(buf) != "01234" || !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("got %q, %v", buf, err)
+	}
+}
@@ -0,0 +1,108 @@
+package router
+
+import "slices"
+
+// loadTable returns the current route-table, building it on first use
+func (r *Router) loadTable() (*routeTable, error) {
+	if table := r.table.Load(); table != nil {
+		return table, nil
+	}
+
+	r.routesMutex.Lock()
+	defer r.routesMutex.Unlock()
+
+	if table := r.table.Load(); table != nil {
+		return table, nil
+	}
+	table, err := r.buildTable()
+	if err != nil {
+		return nil, err
+	}
+	r.table.Store(table)
+	return table, nil
+}
+
+// setRoutes builds a new route-table and swaps it in, the current routes are kept on error.
+// The caller must hold routesMutex.
+func (r *Router) setRoutes(routes []*Route) error {
+	prev := r.routes
+	r.routes = routes
+
+	table, err := r.buildTable()
+	if err != nil {
+		r.routes = prev
+		return err
+	}
+	if old := r.table.Swap(table); old != nil {
+		for _, limiter := range old.limiters {
+			if !slices.Contains(table.limiters, limiter) {
+				limiter.retire()
+			}
+		}
+	}
+	if r.cache != nil {
+		r.cache.invalidate(func(*cacheEntry) bool { return true })
+	}
+	return nil
+}
+
+// Add adds a route to the router, safe to call while the router is serving requests
+func (r *Router) Add(route Route) error {
+	rt, err := r.newRoute(route)
+	if err != nil {
+		return err
+	}
+
+	r.routesMutex.Lock()
+	defer r.routesMutex.Unlock()
+
+	if rt.Name != "" {
+		for _, existing := range r.routes {
+			if existing.Name == rt.Name {
+				return ErrRouteDuplicateName
+			}
+		}
+	}
+
+	routes := make([]*Route, 0, len(r.routes)+1)
+	routes = append(routes, r.routes...)
+	return r.setRoutes(append(routes, rt))
+}
+
+// Remove removes the route(s) with the given name, safe to call while the router is serving requests.
+// Requests already being handled by the removed route are completed. Unnamed routes can't be removed.
+func (r *Router) Remove(name string) error {
+	if name == "" {
+		return ErrRouteNotFound
+	}
+	r.routesMutex.Lock()
+	defer r.routesMutex.Unlock()
+
+	routes := make([]*Route, 0, len(r.routes))
+	for _, existing := range r.routes {
+		if existing.Name != name {
+			routes = append(routes, existing)
+		}
+	}
+	if len(routes) == len(r.routes) {
+		return ErrRouteNotFound
+	}
+	return r.setRoutes(routes)
+}
+
+// Replace replaces all routes of the router, safe to call while the router is serving requests
+func (r *Router) Replace(routes []Route) error {
+	list := make([]*Route, 0, len(routes))
+	for i := range routes {
+		rt, err := r.newRoute(routes[i])
+		if err != nil {
+			return err
+		}
+		list = append(list, rt)
+	}
+
+	r.routesMutex.Lock()
+	defer r.routesMutex.Unlock()
+
+	return r.setRoutes(list)
+}
@@ -0,0 +1,134 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func getStatus(h http.Handler, method, path string) int {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w.Code
+}
+
+func TestRouterAdd(t *testing.T) {
+	r, err := New([]Route{
+		{Name: "a", Method: "GET", Path: "/a", Handler: handlerReturnStatus},
+	}, WithPort(9999))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if got := getStatus(r, "GET", "/b"); got != http.StatusNotFound {
+		t.Errorf("before Add: status = %d, want %d", got, http.StatusNotFound)
+	}
+	if err := r.Add(Route{Name: "b", Method: "GET", Path: "/b", Handler: handlerReturnStatus}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if got := getStatus(r, "GET", "/b"); got != 201 {
+		t.Errorf("after Add: status = %d, want 201", got)
+	}
+
+	if err := r.Add(Route{Name: "b", Method: "GET", Path: "/other", Handler: handlerReturnStatus}); !errors.Is(err, ErrRouteDuplicateName) {
+		t.Errorf("Add(duplicate name) = %v, want ErrRouteDuplicateName", err)
+	}
+	if err := r.Add(Route{Name: "c", Method: "GET", Path: "/a", Handler: handlerReturnStatus}); err == nil {
+		t.Error("Add(conflicting pattern) should return an error")
+	}
+	if got := getStatus(r, "GET", "/a"); got != 201 {
+		t.Errorf("after failed Add: status = %d, want 201", got)
+	}
+}
+
+func TestRouterRemove(t *testing.T) {
+	r, err := New([]Route{
+		{Name: "a", Method: "GET", Path: "/a", Handler: handlerReturnStatus},
+		{Name: "b", Method: "GET", Path: "/b", Handler: handlerReturnStatus},
+		{Method: "GET", Path: "/unnamed", Handler: handlerReturnStatus},
+	}, WithPort(9999))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if err := r.Remove("a"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if got := getStatus(r, "GET", "/a"); got != http.StatusNotFound {
+		t.Errorf("after Remove: status = %d, want %d", got, http.StatusNotFound)
+	}
+	if got := getStatus(r, "GET", "/b"); got != 201 {
+		t.Errorf("other route: status = %d, want 201", got)
+	}
+	if err := r.Remove("a"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("Remove(missing) = %v, want ErrRouteNotFound", err)
+	}
+	if err := r.Remove(""); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("Remove(\"\") = %v, want ErrRouteNotFound", err)
+	}
+	if got := getStatus(r, "GET", "/unnamed"); got != 201 {
+		t.Errorf("unnamed route: status = %d, want 201", got)
+	}
+}
+
+func TestRouterReplaceWhileServing(t *testing.T) {
+	r, err := New([]Route{
+		{Name: "a", Method: "GET", Path: "/a", Handler: handlerReturnStatus},
+	}, WithPort(9999))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if got := getStatus(r, "GET", "/a"); got != 201 && got != 204 {
+					t.Errorf("status = %d during Replace", got)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		handler := interface{}(handlerReturnStatus)
+		if i%2 == 0 {
+			handler = handlerNoArgsNoReturn
+		}
+		if err := r.Replace([]Route{{Name: "a", Method: "GET", Path: "/a", Handler: handler}}); err != nil {
+			t.Fatalf("Replace: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if got := getStatus(r, "GET", "/a"); got != 201 {
+		t.Errorf("after Replace: status = %d, want 201", got)
+	}
+}
+
+func TestNewInvalidRoute(t *testing.T) {
+	tests := []struct {
+		name  string
+		route Route
+		want  error
+	}{
+		{"cors", Route{CORS: &CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}}, ErrorInvalidCORS},
+		{"auth", Route{Auth: &AuthConfig{}}, ErrorInvalidAuth},
+		{"cache", Route{Cache: &CacheConfig{MaxAge: -time.Second}}, ErrorInvalidCache},
+		{"rate-limit", Route{RateLimit: &RateLimitConfig{}}, ErrorInvalidRateLimit},
+		{"concurrency", Route{Concurrency: &ConcurrencyConfig{}}, ErrorInvalidConcurrency},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.route.Name, tc.route.Method, tc.route.Path, tc.route.Handler = "invalid", "GET", "/invalid", handlerReturnStatus
+			if r, err := New([]Route{tc.route}); r != nil || !errors.Is(err, tc.want) {
+				t.Errorf("New = %v, %v, want %v", r, err, tc.want)
+			}
+		})
+	}
+}
@@ -14,9 +14,23 @@ const (
 
 // Our errors
 var (
-	ErrRouterAlreadyRunning = fmt.Errorf("router is already running")
-	ErrInvalidMatch         = fmt.Errorf("invalid match")
-	ErrRouterDuplicateName  = fmt.Errorf("duplicate router name")
+	ErrRouterAlreadyRunning  = fmt.Errorf("router is already running")
+	ErrInvalidMatch          = fmt.Errorf("invalid match")
+	ErrRouterDuplicateName   = fmt.Errorf("duplicate router name")
+	ErrRouteDuplicateName    = fmt.Errorf("duplicate route name")
+	ErrRouteNotFound         = fmt.Errorf("route not found")
+	ErrURLParams             = fmt.Errorf("invalid url parameters")
+	ErrNotFound              = fmt.Errorf("not found")
+	ErrMethodNotAllowed      = fmt.Errorf("method not allowed")
+	ErrTooManyRequests       = fmt.Errorf("too many requests")
+	ErrOverloaded            = fmt.Errorf("service overloaded")
+	ErrUnsupportedEncoding   = fmt.Errorf("unsupported content-encoding")
+	ErrBodyTooLarge          = fmt.Errorf("decompressed body is too large")
+	ErrUnauthorized          = fmt.Errorf("unauthorized")
+	ErrForbidden             = fmt.Errorf("forbidden")
+	ErrIdempotencyKeyMissing = fmt.Errorf("idempotency-key is required")
+	ErrIdempotencyInFlight   = fmt.Errorf("a request with the same idempotency-key is in progress")
+	ErrIdempotencyMismatch   = fmt.Errorf("idempotency-key was used with a different request")
 )
 
 // FieldError is the error-message returned when a parameter (query och path) is invalid
@@ -0,0 +1,40 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group returns a copy of the routes with the prefix prepended to each path and the
+// middlewares applied before any route-specific middlewares.
+//
+// Groups can be nested, and the result is used as (part of) the routes to New or Serve:
+//
+//	routes := append(publicRoutes,
+//		router.Group("/admin", adminRoutes, authMiddleware)...,
+//	)
+func Group(prefix string, routes []Route, mws ...func(http.Handler) http.Handler) []Route {
+	result := make([]Route, 0, len(routes))
+	for _, route := range routes {
+		route.Path = joinPath(prefix, route.Path)
+		if len(mws) > 0 {
+			chain := make([]func(http.Handler) http.Handler, 0, len(mws)+len(route.Middlewares))
+			chain = append(chain, mws...)
+			route.Middlewares = append(chain, route.Middlewares...)
+		}
+		result = append(result, route)
+	}
+	return result
+}
+
+// joinPath adds a prefix to a path, where the path "/" means the prefix itself
+func joinPath(prefix, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return path
+	}
+	if path == "/" || path == "" {
+		return prefix
+	}
+	return prefix + path
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct {
+		prefix string
+		path   string
+		want   string
+	}{
+		{"", "/items", "/items"},
+		{"/api", "/items", "/api/items"},
+		{"/api/", "/items", "/api/items"},
+		{"/api", "/", "/api"},
+		{"/api", "/*", "/api/*"},
+	}
+	for _, tc := range tests {
+		if got := joinPath(tc.prefix, tc.path); got != tc.want {
+			t.Errorf("joinPath(%q, %q) = %q, want %q", tc.prefix, tc.path, got, tc.want)
+		}
+	}
+}
+
+// headerMW returns a middleware appending its name to the X-Chain response header
+func headerMW(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	routes := []Route{
+		{Name: "public", Method: "GET", Path: "/public", Handler: handlerReturnStatus},
+	}
+	routes = append(routes, Group("/api",
+		append(
+			[]Route{{Name: "status", Method: "GET", Path: "/status", Handler: handlerReturnStatus,
+				Middlewares: []func(http.Handler) http.Handler{headerMW("route")}}},
+			Group("/admin", []Route{
+				{Name: "admin", Method: "GET", Path: "/", Handler: handlerReturnStatus},
+			}, headerMW("admin"))...,
+		), headerMW("api"))...)
+
+	h := buildTestHandlerWithOpts(t, routes, WithMiddleware(headerMW("router")))
+
+	tests := []struct {
+		path  string
+		chain string
+	}{
+		{"/public", "router"},
+		{"/api/status", "router,api,route"},
+		{"/api/admin", "router,api,admin"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+			if w.Code != 201 {
+				t.Errorf("status = %d, want 201", w.Code)
+			}
+			if got := strings.Join(w.Header().Values("X-Chain"), ","); got != tc.chain {
+				t.Errorf("middlewares = %q, want %q", got, tc.chain)
+			}
+		})
+	}
+}
+
+func TestGroupDoesNotModifyInput(t *testing.T) {
+	routes := []Route{{Name: "a", Path: "/a", Middlewares: []func(http.Handler) http.Handler{headerMW("a")}}}
+	_ = Group("/x", routes, headerMW("x"))
+	if routes[0].Path != "/a" || len(routes[0].Middlewares) != 1 {
+		t.Errorf("Group modified its input: %+v", routes[0])
+	}
+}
@@ -7,46 +7,17 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
-
-	"github.com/justinas/alice"
-	"github.com/ninlil/butler/log"
 )
 
-// buildTestHandler sets up a Router's ServeMux with routes and returns the http.Handler.
-// It mirrors the route-setup portion of Serve() without binding a real TCP port.
+// buildTestHandler sets up a Router's route-table with routes and returns the http.Handler.
+// It uses the same route-setup as Serve() without binding a real TCP port.
 func buildTestHandler(t *testing.T, routes []Route) http.Handler {
 	t.Helper()
-	allOpts := []Option{WithPort(9999)}
-	r, err := New(routes, allOpts...)
-	if err != nil {
-		t.Fatalf("New: %v", err)
-	}
-	for _, route := range r.routes {
-		method := "GET"
-		if route.Method != "" {
-			method = route.Method
-		}
-		if route.Path == "/" && r.prefix != "" {
-			route.Path = r.prefix
-		} else {
-			route.Path = r.prefix + route.Path
-		}
-		chain := alice.New().Append(wrapWriterMW)
-		chain = chain.Append(log.NewHandler())
-		chain = chain.Append(IDHandler())
-		chain = chain.Append(accessLogger)
-		chain = chain.Append(r.panicHandler)
-		for _, mw := range r.middlewares {
-			chain = chain.Append(alice.Constructor(mw))
-		}
-		handler := chain.ThenFunc(route.wrapHandler())
-		r.router.Handle(buildPattern(method, route.Path), handler)
-	}
-	return r.router
+	return buildTestHandlerWithOpts(t, routes)
 }
 
 // buildTestHandlerWithOpts is like buildTestHandler but accepts additional options
-// (e.g. WithPrefix). Used for the migration baseline tests.
+// (e.g. WithPrefix).
 func buildTestHandlerWithOpts(t *testing.T, routes []Route, extra ...Option) http.Handler {
 	t.Helper()
 	allOpts := append([]Option{WithPort(9999)}, extra...)
@@ -54,28 +25,10 @@ func buildTestHandlerWithOpts(t *testing.T, routes []Route, extra ...Option) htt
 	if err != nil {
 		t.Fatalf("New: %v", err)
 	}
-	for _, route := range r.routes {
-		method := "GET"
-		if route.Method != "" {
-			method = route.Method
-		}
-		if route.Path == "/" && r.prefix != "" {
-			route.Path = r.prefix
-		} else {
-			route.Path = r.prefix + route.Path
-		}
-		chain := alice.New().Append(wrapWriterMW)
-		chain = chain.Append(log.NewHandler())
-		chain = chain.Append(IDHandler())
-		chain = chain.Append(accessLogger)
-		chain = chain.Append(r.panicHandler)
-		for _, mw := range r.middlewares {
-			chain = chain.Append(alice.Constructor(mw))
-		}
-		handler := chain.ThenFunc(route.wrapHandler())
-		r.router.Handle(buildPattern(method, route.Path), handler)
+	if _, err := r.loadTable(); err != nil {
+		t.Fatalf("loadTable: %v", err)
 	}
-	return r.router
+	return r
 }
 
 // --- test handlers and args structs ---
@@ -484,9 +437,8 @@ func TestMethodMatch(t *testing.T) {
 }
 
 func TestMethodMismatch(t *testing.T) {
-	// net/http ServeMux (Go 1.22+) returns 405 Method Not Allowed when a path is registered
-	// for a specific method and a request arrives with a different method. This matches chi's
-	// behaviour, so no status change was needed during the chi-to-stdlib migration.
+	// A path registered for a specific method returns 405 Method Not Allowed, rendered by the
+	// router (not the ServeMux) with an Allow header and the regular error body.
 	h := buildTestHandler(t, []Route{
 		{Name: "get-only", Method: "GET", Path: "/get-only", Handler: handlerNoArgsNoReturn},
 	})
@@ -497,6 +449,21 @@ func TestMethodMismatch(t *testing.T) {
 		t.Errorf("status = %d, want %d",
 			w.Code, http.StatusMethodNotAllowed)
 	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Errorf("Allow = %q, want %q", allow, "GET, HEAD, OPTIONS")
+	}
+	if w.Header().Get("X-Request-Id") == "" {
+		t.Error("expected X-Request-Id on 405 response")
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not valid JSON: %v", err)
+	}
+	if body.Error != ErrMethodNotAllowed.Error() {
+		t.Errorf("error = %q, want %q", body.Error, ErrMethodNotAllowed.Error())
+	}
 }
 
 func TestMethodWildcard(t *testing.T) {
@@ -0,0 +1,280 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ninlil/butler/auth"
+	"github.com/ninlil/butler/bufferedresponse"
+	"github.com/ninlil/butler/clock"
+	"github.com/ninlil/butler/log"
+)
+
+const (
+	defaultIdempotencyHeader = "Idempotency-Key"
+	defaultIdempotencyTTL    = 24 * time.Hour
+	hdrIdempotentReplayed    = "Idempotent-Replayed"
+)
+
+// IdempotencyConfig makes a route idempotent for requests with an 'Idempotency-Key'
+type IdempotencyConfig struct {
+	// Header with the key of the client (default "Idempotency-Key")
+	Header string
+
+	// TTL is how long the responses are kept (default 24 hours)
+	TTL time.Duration
+
+	// Required rejects requests without a key (400)
+	Required bool
+
+	// Scope separates the keys of different clients, ex by the client-ip
+	// (default the subject of the principal for authenticated requests)
+	Scope func(*http.Request) string
+
+	// Shared shares the keys between all clients, also authenticated ones, when there's no Scope.
+	// Any client repeating a key gets the stored response, without the handler authorizing it.
+	Shared bool
+
+	// Store keeps the responses (default NewIdempotencyMemoryStore)
+	Store IdempotencyStore
+}
+
+// IdempotentResponse is a stored response, replayed for repeated requests
+type IdempotentResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// IdempotencyRecord is the state of a key
+type IdempotencyRecord struct {
+	// Fingerprint of the request (method, path and body) that claimed the key,
+	// not including the principal (see IdempotencyConfig.Scope)
+	Fingerprint string `json:"fingerprint"`
+
+	// Response of the request, nil while it's in progress
+	Response *IdempotentResponse `json:"response,omitempty"`
+}
+
+// IdempotencyStore keeps the state of the idempotency-keys, implement it to share them between instances
+type IdempotencyStore interface {
+	// Begin claims the key for a request, returning nil if claimed, or the record of an earlier request with the key
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error)
+
+	// Complete stores the response of the request that claimed the key
+	Complete(ctx context.Context, key string, response *IdempotentResponse, ttl time.Duration) error
+
+	// Release removes the claim of a request without a response to keep, so it can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// idempotency is an IdempotencyConfig with its defaults applied
+type idempotency struct {
+	IdempotencyConfig
+	prefix string
+}
+
+// initIdempotency sets up the idempotency of the route
+func (rt *Route) initIdempotency() error {
+	cfg := rt.Idempotency
+	if cfg == nil {
+		return nil
+	}
+	if cfg.TTL < 0 {
+		return ErrorInvalidIdempotency
+	}
+	idem := &idempotency{IdempotencyConfig: *cfg, prefix: "route:" + rt.id() + ":"}
+	if idem.Header == "" {
+		idem.Header = defaultIdempotencyHeader
+	}
+	if idem.TTL == 0 {
+		idem.TTL = defaultIdempotencyTTL
+	}
+	if idem.Store == nil {
+		idem.Store = NewIdempotencyMemoryStore()
+	}
+	rt.idempotency = idem
+	return nil
+}
+
+// fingerprint identifies the request by method, path and body, leaving the body readable
+func fingerprint(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// handler is the middleware replaying the responses of repeated requests
+func (idem *idempotency) handler(rt *Route) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w2, ok := bufferedresponse.Get(w)
+			if !ok || !isUnsafe(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			clientKey := r.Header.Get(idem.Header)
+			if clientKey == "" {
+				if idem.Required {
+					rt.writeError(ErrIdempotencyKeyMissing, w, r, http.StatusBadRequest)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fp, err := fingerprint(r)
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, ErrBodyTooLarge) {
+					status = http.StatusRequestEntityTooLarge
+				}
+				rt.writeError(err, w, r, status)
+				return
+			}
+			ctx := r.Context()
+			key := idem.prefix
+			switch p, found := auth.PrincipalFromCtx(ctx); {
+			case idem.Scope != nil:
+				key += idem.Scope(r) + ":"
+			case found && p != nil && !idem.Shared:
+				key += "principal=" + p.Method + "/" + p.Subject + ":"
+			}
+			key += clientKey
+
+			record, err := idem.Store.Begin(ctx, key, fp, idem.TTL)
+			switch {
+			case err != nil:
+				log.FromCtx(ctx).Warn().Msgf("router: idempotency-store failed, handling the request: %v", err)
+				next.ServeHTTP(w, r)
+				return
+
+			case record == nil:
+				idem.serve(next, w2, r, key)
+				return
+
+			case record.Fingerprint != fp:
+				rt.writeError(ErrIdempotencyMismatch, w, r, http.StatusUnprocessableEntity)
+
+			case record.Response == nil:
+				w.Header().Set("Retry-After", "1")
+				rt.writeError(ErrIdempotencyInFlight, w, r, http.StatusConflict)
+
+			default:
+				for name, values := range record.Response.Header {
+					w.Header()[name] = slices.Clone(values)
+				}
+				w.Header().Set(hdrIdempotentReplayed, "true")
+				w.WriteHeader(record.Response.Status)
+				_, _ = w.Write(record.Response.Body)
+			}
+		})
+	}
+}
+
+// serve handles the request that claimed the key, storing its response (unless it failed with a 5xx or a panic)
+func (idem *idempotency) serve(next http.Handler, w *bufferedresponse.ResponseWriter, r *http.Request, key string) {
+	ctx := r.Context()
+	completed := false
+	defer func() {
+		if !completed {
+			if err := idem.Store.Release(context.WithoutCancel(ctx), key); err != nil {
+				log.FromCtx(ctx).Warn().Msgf("router: idempotency-store failed to release the key: %v", err)
+			}
+		}
+	}()
+
+	before := w.Header().Clone()
+	next.ServeHTTP(w, r)
+	if w.Status() >= http.StatusInternalServerError {
+		return
+	}
+
+	resp := &IdempotentResponse{
+		Status: w.Status(),
+		Header: headerChanges(before, w.Header()),
+		Body:   slices.Clone(w.Bytes()),
+	}
+	if err := idem.Store.Complete(context.WithoutCancel(ctx), key, resp, idem.TTL); err != nil {
+		log.FromCtx(ctx).Warn().Msgf("router: idempotency-store failed to store the response: %v", err)
+		return
+	}
+	completed = true
+}
+
+// isUnsafe is true for methods that change state
+func isUnsafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	return true
+}
+
+type idempotencyEntry struct {
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+// IdempotencyMemoryStore is an in-memory IdempotencyStore, for a single instance
+type IdempotencyMemoryStore struct {
+	ms memoryStore[idempotencyEntry]
+}
+
+// NewIdempotencyMemoryStore creates an in-memory idempotency-store
+func NewIdempotencyMemoryStore() *IdempotencyMemoryStore {
+	return &IdempotencyMemoryStore{ms: memoryStore[idempotencyEntry]{now: clock.Now}}
+}
+
+// Begin claims the key, unless it has a record that hasn't expired
+func (s *IdempotencyMemoryStore) Begin(_ context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error) {
+	s.ms.mutex.Lock()
+	defer s.ms.mutex.Unlock()
+
+	now := s.ms.now()
+	e := s.ms.entry(key, now, ttl, func(e *idempotencyEntry) bool {
+		return !now.Before(e.expires)
+	})
+	if !e.expires.IsZero() && now.Before(e.expires) {
+		record := e.record
+		return &record, nil
+	}
+	*e = idempotencyEntry{record: IdempotencyRecord{Fingerprint: fingerprint}, expires: now.Add(ttl)}
+	return nil, nil
+}
+
+// Complete stores the response of the key
+func (s *IdempotencyMemoryStore) Complete(_ context.Context, key string, response *IdempotentResponse, ttl time.Duration) error {
+	s.ms.mutex.Lock()
+	defer s.ms.mutex.Unlock()
+
+	if e, found := s.ms.entries[key]; found {
+		e.record.Response = response
+		e.expires = s.ms.now().Add(ttl)
+	}
+	return nil
+}
+
+// Release removes the key
+func (s *IdempotencyMemoryStore) Release(_ context.Context, key string) error {
+	s.ms.mutex.Lock()
+	defer s.ms.mutex.Unlock()
+
+	delete(s.ms.entries, key)
+	return nil
+}
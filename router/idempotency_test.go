@@ -0,0 +1,194 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ninlil/butler/auth"
+)
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int32
+	create := func(w http.ResponseWriter, args *bodyArgs) (*testItem, int) {
+		id := int(calls.Add(1))
+		w.Header().Set("Location", fmt.Sprintf("/items/%d", id))
+		return &testItem{ID: id, Name: args.Body.Name}, http.StatusCreated
+	}
+	failing := func() (int, error) {
+		calls.Add(1)
+		return http.StatusServiceUnavailable, errors.New("failed")
+	}
+	routes := []Route{
+		{Name: "create", Method: "POST", Path: "/items", Handler: create, Idempotency: &IdempotencyConfig{}},
+		{Name: "strict", Method: "POST", Path: "/strict", Handler: create, Idempotency: &IdempotencyConfig{Required: true}},
+		{Name: "failing", Method: "POST", Path: "/failing", Handler: failing, Idempotency: &IdempotencyConfig{}},
+	}
+	h := buildTestHandler(t, routes)
+
+	post := func(path, key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("/items", "k1", `{"name":"a"}`)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first: %d %v", first.Code, first.Header())
+	}
+	repeat := post("/items", "k1", `{"name":"a"}`)
+	if repeat.Code != http.StatusCreated || repeat.Body.String() != first.Body.String() ||
+		repeat.Header().Get("Location") != first.Header().Get("Location") || repeat.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("repeat: %d %q %v, want %q", repeat.Code, repeat.Body.String(), repeat.Header(), first.Body.String())
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+
+	if w := post("/items", "k1", `{"name":"b"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("other body: %d %s", w.Code, w.Body.String())
+	}
+	if w := post("/items", "k2", `{"name":"a"}`); w.Code != http.StatusCreated || w.Body.String() == first.Body.String() {
+		t.Errorf("other key: %d %s", w.Code, w.Body.String())
+	}
+	if w := post("/items", "", `{"name":"a"}`); w.Code != http.StatusCreated {
+		t.Errorf("without key: %d", w.Code)
+	}
+	if w := post("/strict", "", `{"name":"a"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("required key: %d", w.Code)
+	}
+
+	calls.Store(0)
+	post("/failing", "k1", "")
+	post("/failing", "k1", "")
+	if calls.Load() != 2 {
+		t.Errorf("failed request should be retried, called %d times", calls.Load())
+	}
+}
+
+func TestIdempotencyUnnamedRoutes(t *testing.T) {
+	create := func(args *bodyArgs) (*testItem, int) {
+		return &testItem{Name: args.Body.Name}, http.StatusCreated
+	}
+	idem := &IdempotencyConfig{Store: NewIdempotencyMemoryStore()}
+	routes := []Route{
+		{Method: "POST", Path: "/x", Handler: create, Idempotency: idem},
+		{Method: "POST", Path: "/y", Handler: create, Idempotency: idem},
+	}
+	h := buildTestHandler(t, routes)
+
+	// unnamed routes sharing a store keep their keys apart
+	for _, path := range []string{"/x", "/y"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"name":"a"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "k1")
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+			t.Errorf("%s: %d %v", path, w.Code, w.Header())
+		}
+	}
+}
+
+func TestIdempotencyPrincipal(t *testing.T) {
+	keys := &auth.APIKey{Lookup: auth.StaticKeys(map[string]auth.Principal{
+		"a": {Subject: "alice"},
+		"b": {Subject: "bob"},
+	})}
+	var calls atomic.Int32
+	create := func() (*testItem, int) {
+		return &testItem{ID: int(calls.Add(1))}, http.StatusCreated
+	}
+	routes := []Route{
+		{Name: "own", Method: "POST", Path: "/own", Handler: create, Idempotency: &IdempotencyConfig{}},
+		{Name: "shared", Method: "POST", Path: "/shared", Handler: create, Idempotency: &IdempotencyConfig{Shared: true}},
+	}
+	h := buildTestHandlerWithOpts(t, routes, WithAuth(AuthConfig{Authenticator: keys}))
+
+	post := func(path, apiKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		req.Header.Set("Idempotency-Key", "k1")
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	alice := post("/own", "a")
+	if bob := post("/own", "b"); bob.Code != http.StatusCreated || bob.Body.String() == alice.Body.String() ||
+		bob.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("bob got the response of alice: %d %q", bob.Code, bob.Body.String())
+	}
+	if again := post("/own", "a"); again.Body.String() != alice.Body.String() || again.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("alice repeating: %q, want %q", again.Body.String(), alice.Body.String())
+	}
+
+	alice = post("/shared", "a")
+	if bob := post("/shared", "b"); bob.Body.String() != alice.Body.String() || bob.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("shared: %q, want %q", bob.Body.String(), alice.Body.String())
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := func() string {
+		close(started)
+		<-release
+		return "done"
+	}
+	h := buildTestHandler(t, []Route{{Name: "slow", Method: "POST", Path: "/slow", Handler: slow, Idempotency: &IdempotencyConfig{}}})
+
+	post := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/slow", nil)
+		req.Header.Set("Idempotency-Key", "k")
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post() }()
+	<-started
+
+	if w := post(); w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("in-flight duplicate: %d %v", w.Code, w.Header())
+	}
+	close(release)
+	if w := <-done; w.Code != http.StatusOK {
+		t.Errorf("first request: %d", w.Code)
+	}
+	if w := post(); w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("after completion: %d %v", w.Code, w.Header())
+	}
+}
+
+func TestIdempotencyMemoryStoreTTL(t *testing.T) {
+	s := NewIdempotencyMemoryStore()
+	clock := time.Now()
+	s.ms.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	if rec, _ := s.Begin(ctx, "k", "fp", time.Minute); rec != nil {
+		t.Fatalf("new key: %+v", rec)
+	}
+	_ = s.Complete(ctx, "k", &IdempotentResponse{Status: http.StatusOK}, time.Minute)
+	if rec, _ := s.Begin(ctx, "k", "fp", time.Minute); rec == nil || rec.Response == nil {
+		t.Fatalf("stored key: %+v", rec)
+	}
+	clock = clock.Add(time.Minute)
+	if rec, _ := s.Begin(ctx, "k", "fp", time.Minute); rec != nil {
+		t.Errorf("expired key: %+v", rec)
+	}
+}
@@ -0,0 +1,231 @@
+package router
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/ninlil/butler/clock"
+	"github.com/rs/xid"
+)
+
+// IDGenerator creates a new unique id for requests and correlations
+type IDGenerator func() string
+
+// XID generates a 20 character xid (the default)
+func XID() string {
+	if !clock.IsFake() {
+		return xid.New().String()
+	}
+	// the time and the random bytes from the fake clock, the counter of a real xid is replaced by random bytes too
+	var id [12]byte
+	binary.BigEndian.PutUint32(id[:4], uint32(clock.Now().Unix()))
+	randomBytes(id[4:])
+	x, _ := xid.FromBytes(id[:])
+	return x.String()
+}
+
+// UUIDv4 generates a random UUID (version 4)
+func UUIDv4() string {
+	var id [16]byte
+	randomBytes(id[:])
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return formatUUID(id)
+}
+
+// UUIDv7 generates a time-ordered UUID (version 7)
+func UUIDv7() string {
+	var id [16]byte
+	randomBytes(id[6:])
+	putMillis(id[:6], clock.Now())
+	id[6] = (id[6] & 0x0f) | 0x70
+	id[8] = (id[8] & 0x3f) | 0x80
+	return formatUUID(id)
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates a time-ordered ULID (26 characters, Crockford base32)
+func ULID() string {
+	var id [16]byte
+	randomBytes(id[6:])
+	putMillis(id[:6], clock.Now())
+
+	// 128 bits as 26 characters of 5 bits, the first character only holds 3 bits
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var buf [26]byte
+	for i := 25; i >= 0; i-- {
+		buf[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf[:])
+}
+
+func randomBytes(buf []byte) {
+	clock.Read(buf)
+}
+
+// putMillis stores the unix-time in milliseconds as 48 bits big-endian
+func putMillis(buf []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		buf[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+func formatUUID(id [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+	return string(buf[:])
+}
+
+type idConfig struct {
+	requestHeader     string
+	correlationHeader string
+	requestField      string
+	correlationField  string
+	generator         IDGenerator
+	trustIncoming     bool
+	validator         func(string) bool
+	maxLength         int
+	echo              bool
+}
+
+// defaultIDConfig validates incoming ids outside of IDHandler
+var defaultIDConfig = newIDConfig()
+
+// IDOption is for 'functional options' to IDHandler and WithIDs
+type IDOption func(*idConfig)
+
+func newIDConfig(opts ...IDOption) *idConfig {
+	cfg := &idConfig{
+		requestHeader:     requestID,
+		correlationHeader: correlationID,
+		requestField:      "req_id",
+		correlationField:  "corr_id",
+		generator:         XID,
+		trustIncoming:     true,
+		maxLength:         128,
+		echo:              true,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithIDs configures the request- and correlation-ids of the router, see IDHandler
+func WithIDs(opts ...IDOption) Option {
+	return func(r *Router) error {
+		r.idOptions = append(r.idOptions, opts...)
+		return nil
+	}
+}
+
+// WithIDHeaders sets the names of the request- and correlation-id headers
+// (default "X-Request-Id" and "X-Correlation-Id")
+func WithIDHeaders(request, correlation string) IDOption {
+	return func(cfg *idConfig) {
+		if request != "" {
+			cfg.requestHeader = http.CanonicalHeaderKey(request)
+		}
+		if correlation != "" {
+			cfg.correlationHeader = http.CanonicalHeaderKey(correlation)
+		}
+	}
+}
+
+// WithIDFields sets the names of the request- and correlation-id log fields (default "req_id" and "corr_id")
+func WithIDFields(request, correlation string) IDOption {
+	return func(cfg *idConfig) {
+		if request != "" {
+			cfg.requestField = request
+		}
+		if correlation != "" {
+			cfg.correlationField = correlation
+		}
+	}
+}
+
+// WithIDGenerator sets the generator for new ids (ex: XID, UUIDv4, UUIDv7 or ULID)
+func WithIDGenerator(gen IDGenerator) IDOption {
+	return func(cfg *idConfig) {
+		if gen != nil {
+			cfg.generator = gen
+		}
+	}
+}
+
+// WithTrustedIDs sets if ids in the request headers are used (default true), or always replaced
+func WithTrustedIDs(flag bool) IDOption {
+	return func(cfg *idConfig) {
+		cfg.trustIncoming = flag
+	}
+}
+
+// WithIDMaxLength sets the max length of an incoming id (default 128), longer ids are replaced
+func WithIDMaxLength(n int) IDOption {
+	return func(cfg *idConfig) {
+		cfg.maxLength = n
+	}
+}
+
+// WithIDValidator replaces the check of the characters in an incoming id,
+// the default allows letters, digits and "-_.:"
+func WithIDValidator(fn func(string) bool) IDOption {
+	return func(cfg *idConfig) {
+		cfg.validator = fn
+	}
+}
+
+// WithIDEcho sets if the ids are returned as response headers (default true)
+func WithIDEcho(flag bool) IDOption {
+	return func(cfg *idConfig) {
+		cfg.echo = flag
+	}
+}
+
+// incoming returns the id from the request header, if trusted and valid
+func (cfg *idConfig) incoming(r *http.Request, header string) string {
+	if !cfg.trustIncoming {
+		return ""
+	}
+	id := r.Header.Get(header)
+	if id == "" || (cfg.maxLength > 0 && len(id) > cfg.maxLength) {
+		return ""
+	}
+	validator := cfg.validator
+	if validator == nil {
+		validator = isSafeID
+	}
+	if !validator(id) {
+		return ""
+	}
+	return id
+}
+
+// isSafeID checks that the id only contains characters that are safe to log and return
+func isSafeID(id string) bool {
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ninlil/butler/clock/clocktest"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+)
+
+func TestIDGenerators(t *testing.T) {
+	tests := []struct {
+		name string
+		gen  IDGenerator
+		re   string
+	}{
+		{"XID", XID, `^[0-9a-v]{20}$`},
+		{"UUIDv4", UUIDv4, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"UUIDv7", UUIDv7, `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"ULID", ULID, `^[0-7][0-9A-HJKMNP-TV-Z]{25}$`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			re := regexp.MustCompile(tc.re)
+			a, b := tc.gen(), tc.gen()
+			if !re.MatchString(a) {
+				t.Errorf("%s() = %q, does not match %s", tc.name, a, tc.re)
+			}
+			if a == b {
+				t.Errorf("%s() returned the same id twice: %q", tc.name, a)
+			}
+		})
+	}
+}
+
+func TestTimeOrderedIDs(t *testing.T) {
+	for name, gen := range map[string]IDGenerator{"UUIDv7": UUIDv7, "ULID": ULID} {
+		a := gen()
+		time.Sleep(2 * time.Millisecond)
+		b := gen()
+		if a >= b {
+			t.Errorf("%s: %q should sort before %q", name, a, b)
+		}
+	}
+}
+
+func TestIDGeneratorsFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	generate := func() (ids []string) {
+		t.Run("generate", func(t *testing.T) {
+			clocktest.UseFake(t, start)
+			ids = []string{XID(), UUIDv4(), UUIDv7(), ULID(), newNonce()}
+		})
+		return ids
+	}
+	first, second := generate(), generate()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("%q != %q with the same fake clock", first[i], second[i])
+		}
+	}
+	if id, _ := xid.FromString(first[0]); !id.Time().Equal(start) {
+		t.Errorf("the time of the xid is %v, want %v", id.Time(), start)
+	}
+	if first[3][:10] != "01HK421P48" {
+		t.Errorf("the time of the ULID %q is not %v", first[3], start)
+	}
+}
+
+func TestIDHandlerOptions(t *testing.T) {
+	var buf bytes.Buffer
+	orgLogger := zlog.Logger
+	zlog.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { zlog.Logger = orgLogger })
+
+	routes := []Route{{Name: "status", Method: "GET", Path: "/status", Handler: handlerReturnStatus}}
+
+	serve := func(h http.Handler, headers map[string]string) *httptest.ResponseRecorder {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/status", nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("custom headers, fields and generator", func(t *testing.T) {
+		h := buildTestHandlerWithOpts(t, routes, WithIDs(
+			WithIDHeaders("Request-Id", "Correlation-Id"),
+			WithIDFields("rid", "cid"),
+			WithIDGenerator(func() string { return "generated" }),
+		))
+		w := serve(h, map[string]string{"Correlation-Id": "incoming"})
+		if got := w.Header().Get("Request-Id"); got != "generated" {
+			t.Errorf("Request-Id = %q, want %q", got, "generated")
+		}
+		if got := w.Header().Get("Correlation-Id"); got != "incoming" {
+			t.Errorf("Correlation-Id = %q, want %q", got, "incoming")
+		}
+		if w.Header().Get("X-Request-Id") != "" {
+			t.Error("default header should not be used")
+		}
+		if !strings.Contains(buf.String(), `"rid":"generated"`) || !strings.Contains(buf.String(), `"cid":"incoming"`) {
+			t.Errorf("log = %q, want custom fields", buf.String())
+		}
+	})
+
+	t.Run("invalid incoming ids are replaced", func(t *testing.T) {
+		h := buildTestHandlerWithOpts(t, routes, WithIDs(WithIDMaxLength(10)))
+		w := serve(h, map[string]string{
+			"X-Request-Id":     "bad\"id",
+			"X-Correlation-Id": "much-too-long-id",
+		})
+		if got := w.Header().Get("X-Request-Id"); got == "bad\"id" || got == "" {
+			t.Errorf("X-Request-Id = %q, want a new id", got)
+		}
+		if got := w.Header().Get("X-Correlation-Id"); got == "much-too-long-id" || got == "" {
+			t.Errorf("X-Correlation-Id = %q, want a new id", got)
+		}
+	})
+
+	t.Run("untrusted incoming ids", func(t *testing.T) {
+		h := buildTestHandlerWithOpts(t, routes, WithIDs(WithTrustedIDs(false), WithIDGenerator(UUIDv4)))
+		w := serve(h, map[string]string{"X-Request-Id": "valid-id"})
+		if got := w.Header().Get("X-Request-Id"); got == "valid-id" || len(got) != 36 {
+			t.Errorf("X-Request-Id = %q, want a new UUID", got)
+		}
+	})
+
+	t.Run("no echo", func(t *testing.T) {
+		h := buildTestHandlerWithOpts(t, routes, WithIDs(WithIDEcho(false)))
+		w := serve(h, nil)
+		if w.Header().Get("X-Request-Id") != "" || w.Header().Get("traceparent") != "" {
+			t.Errorf("headers = %v, want no ids", w.Header())
+		}
+		if !strings.Contains(buf.String(), `"req_id":"`) {
+			t.Errorf("log = %q, want req_id", buf.String())
+		}
+	})
+}
+
+func TestIDsFromRequest(t *testing.T) {
+	var rid, cid string
+	h := IDHandler(WithTrustedIDs(false), WithIDGenerator(func() string { return "generated" }))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rid, cid = ReqIDFromRequest(r), CorrIDFromRequest(r)
+		}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-Id", "from-client")
+	req.Header.Set("X-Correlation-Id", "from-client")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if rid != "generated" || cid != "generated" {
+		t.Errorf("untrusted: ids = %q, %q, want the generated ones", rid, cid)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-Id", "valid-id")
+	req.Header.Set("X-Correlation-Id", "bad\"id")
+	if rid, cid := ReqIDFromRequest(req), CorrIDFromRequest(req); rid != "valid-id" || cid != "" {
+		t.Errorf("without IDHandler: ids = %q, %q, want only the valid one", rid, cid)
+	}
+}
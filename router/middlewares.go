@@ -5,9 +5,9 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
-	"time"
 
 	"github.com/ninlil/butler/bufferedresponse"
+	"github.com/ninlil/butler/tracing"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
 )
@@ -50,48 +50,42 @@ func (r *Router) panicHandler(next http.Handler) http.Handler {
 	})
 }
 
-func accessLogger(next http.Handler) http.Handler {
+// tracingHandler creates the server-span of the request, continuing the trace-context from IDHandler
+func tracingHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w2, _ := bufferedresponse.Get(w)
-		log := hlog.FromRequest(r)
-
-		start := time.Now()
-		next.ServeHTTP(w2, r)
-		dur := time.Since(start)
+		if !tracing.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		var e *zerolog.Event
-		switch true {
-		case w2.Status() < 200:
-			e = log.Debug()
-		case w2.Status() < 400:
-			e = log.Info()
-		case w2.Status() < 500:
-			e = log.Warn()
-		default:
-			e = log.Error()
+		tc, _ := TraceFromCtx(r.Context())
+		name := r.Method
+		opts := []tracing.SpanOption{
+			tracing.WithKind(tracing.KindServer),
+			tracing.WithRemoteParent(tc.TraceID, tc.ParentID, tc.Sampled()),
+			tracing.WithSpanID(tc.SpanID),
+			tracing.WithAttr("http.request.method", r.Method),
+			tracing.WithAttr("url.path", r.URL.Path),
+		}
+		if route := RouteFromRequest(r); route != nil {
+			path := route.router.fullPath(route.Path)
+			name += " " + path
+			opts = append(opts, tracing.WithAttr("http.route", path))
+			if route.Name != "" {
+				opts = append(opts, tracing.WithAttr("butler.route", route.Name))
+			}
 		}
 
-		e.Dur("duration", dur)
-		e.Int("status", w2.Status())
-		e.Int("size", w2.Size())
+		ctx, span := tracing.Start(r.Context(), name, opts...)
+		defer span.End()
 
-		// 'chi' doesn't have a way to get the route name
-		// if route != nil {
-		// 	e.Str("route", route.GetName())
-		// 	// if path, err := route.GetPathTemplate(); err == nil {
-		// 	// 	e.Str("path", path)
-		// 	// }
-		// }
+		next.ServeHTTP(w, r.WithContext(ctx))
 
-		e.Msgf("%s %s", r.Method, r.URL.Path)
+		if w2, ok := bufferedresponse.Get(w); ok {
+			span.SetAttr("http.response.status_code", w2.Status())
+			if w2.Status() >= 500 {
+				span.SetStatus(tracing.StatusError, http.StatusText(w2.Status()))
+			}
+		}
 	})
-	// hlog.FromRequest(r).Info().
-	// Str("method", r.Method).
-	// Stringer("url", r.URL).
-	// Int("status", status).
-	// Int("size", size).
-	// Dur("duration", duration).
-	// Msg("")
-
-	// return nil
 }
@@ -0,0 +1,54 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ninlil/butler/tracing"
+)
+
+func TestTracingHandler(t *testing.T) {
+	exp := tracing.NewMemoryExporter()
+	tracing.Enable(exp)
+	t.Cleanup(tracing.Disable)
+
+	h := buildTestHandlerWithOpts(t, []Route{
+		{Name: "item", Method: "GET", Path: "/items/{id}", Handler: handlerPathParam},
+	}, WithPrefix("/api"))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/items/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(w, req)
+	tracing.Flush()
+
+	server := exp.Find("GET /api/items/{id}")
+	if server == nil {
+		t.Fatalf("no server span, got %+v", exp.Spans())
+	}
+	tc, _ := ParseTraceParent(w.Header().Get("traceparent"))
+	if server.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || server.ParentID != "00f067aa0ba902b7" || server.SpanID != tc.ParentID {
+		t.Errorf("server span ids = %s/%s/%s, want them to match the trace-context", server.TraceID, server.ParentID, server.SpanID)
+	}
+	if server.Kind != tracing.KindServer {
+		t.Errorf("kind = %d, want %d", server.Kind, tracing.KindServer)
+	}
+	if got := server.Attr("http.response.status_code"); got != http.StatusOK {
+		t.Errorf("status attribute = %v, want %d", got, http.StatusOK)
+	}
+	if got := server.Attr("butler.route"); got != "item" {
+		t.Errorf("route attribute = %v, want %q", got, "item")
+	}
+
+	for _, name := range []string{"bind", "serialize"} {
+		child := exp.Find(name)
+		if child == nil {
+			t.Errorf("no %q span", name)
+			continue
+		}
+		if child.ParentID != server.SpanID {
+			t.Errorf("%q span is not a child of the server span", name)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package router
+
+import "net/http"
+
+// WithNotFound sets the handler for requests not matching any route.
+//
+// The handler accepts the same arguments and return-values as a regular route-handler,
+// but the status defaults to 404 instead of 200/204 when none is returned.
+func WithNotFound(handler interface{}) Option {
+	return func(r *Router) error {
+		route := &Route{Name: "not_found", Handler: handler, defaultStatus: http.StatusNotFound, router: r}
+		if handler == nil {
+			return errHandlerNotAFunc(*route)
+		}
+		if err := route.init(); err != nil {
+			return err
+		}
+		r.notFound = route
+		return nil
+	}
+}
+
+func defaultNotFound() error {
+	return ErrNotFound
+}
+
+// notFoundRoute returns the route used for unmatched requests
+func (r *Router) notFoundRoute() *Route {
+	if r.notFound != nil {
+		return r.notFound
+	}
+	route := &Route{Name: "not_found", Handler: defaultNotFound, defaultStatus: http.StatusNotFound, router: r}
+	_ = route.init()
+	return route
+}
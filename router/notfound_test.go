@@ -0,0 +1,68 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func handlerNotFound(r *http.Request) testItem {
+	return testItem{Name: r.URL.Path}
+}
+
+func TestWithNotFound(t *testing.T) {
+	h := buildTestHandlerWithOpts(t, []Route{
+		{Name: "item", Method: "GET", Path: "/item", Handler: handlerReturnStruct},
+	}, WithNotFound(handlerNotFound))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w.Header().Get("X-Request-Id") == "" {
+		t.Error("expected X-Request-Id on 404 response")
+	}
+	var got testItem
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("body is not valid JSON: %v", err)
+	}
+	if got.Name != "/missing" {
+		t.Errorf("name = %q, want %q", got.Name, "/missing")
+	}
+}
+
+func TestWithNotFoundStatus(t *testing.T) {
+	h := buildTestHandlerWithOpts(t, nil, WithNotFound(handlerReturnStatus))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != 201 {
+		t.Errorf("status = %d, want 201 (returned status should win)", w.Code)
+	}
+}
+
+func TestWithNotFoundInvalid(t *testing.T) {
+	if _, err := New(nil, WithNotFound(nil)); err == nil {
+		t.Error("WithNotFound(nil) should return an error")
+	}
+	if _, err := New(nil, WithNotFound("not a function")); err == nil {
+		t.Error("WithNotFound(string) should return an error")
+	}
+}
+
+func TestNotFoundXML(t *testing.T) {
+	h := buildTestHandler(t, nil)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/missing", nil)
+	req.Header.Set("Accept", "application/xml")
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ctXML {
+		t.Errorf("Content-Type = %q, want %q", ct, ctXML)
+	}
+}
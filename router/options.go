@@ -3,6 +3,7 @@ package router
 import (
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // Option is for 'functional options' to the New and Serve-methods
@@ -92,6 +93,18 @@ func WithoutReady() Option {
 	}
 }
 
+// WithPreStopDelay sets how long a shutdown waits, after the readiness-probe has started failing,
+// before the listeners are closed. This gives the load balancer time to deregister the pod.
+func WithPreStopDelay(d time.Duration) Option {
+	return func(r *Router) error {
+		if d < 0 {
+			return ErrorInvalidDelay
+		}
+		r.preStopDelay = d
+		return nil
+	}
+}
+
 // WithExposedErrors will send any panic-errors as request-body
 func WithExposedErrors() Option {
 	return func(r *Router) error {
@@ -120,6 +133,16 @@ const (
 	ErrorRequireLeadingSlash Error = 1
 	ErrorNotValidURL         Error = 2
 	ErrorInvalidPort         Error = 3
+	ErrorInvalidDelay        Error = 4
+	ErrorInvalidProxy        Error = 5
+	ErrorInvalidRateLimit    Error = 6
+	ErrorInvalidConcurrency  Error = 7
+	ErrorInvalidCompression  Error = 8
+	ErrorInvalidAuth         Error = 9
+	ErrorInvalidCache        Error = 10
+	ErrorInvalidIdempotency  Error = 11
+	ErrorInvalidRecorder     Error = 12
+	ErrorInvalidCORS         Error = 13
 )
 
 func (err Error) Error() string {
@@ -130,6 +153,26 @@ func (err Error) Error() string {
 		return "not a valid url path"
 	case ErrorInvalidPort:
 		return "invalid port"
+	case ErrorInvalidDelay:
+		return "invalid delay"
+	case ErrorInvalidProxy:
+		return "invalid trusted proxy"
+	case ErrorInvalidRateLimit:
+		return "invalid rate-limit"
+	case ErrorInvalidConcurrency:
+		return "invalid concurrency limit"
+	case ErrorInvalidCompression:
+		return "invalid compression"
+	case ErrorInvalidAuth:
+		return "invalid authentication, an authenticator is required"
+	case ErrorInvalidCache:
+		return "invalid cache"
+	case ErrorInvalidIdempotency:
+		return "invalid idempotency"
+	case ErrorInvalidRecorder:
+		return "invalid recorder, a sink is required"
+	case ErrorInvalidCORS:
+		return "invalid CORS, the origin \"*\" can't be combined with credentials"
 	}
 	return "unknown router error"
 }
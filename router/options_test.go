@@ -3,6 +3,7 @@ package router
 import (
 	"errors"
 	"testing"
+	"time"
 )
 
 func TestIsValidProbePath(t *testing.T) {
@@ -109,3 +110,22 @@ func TestWithReady(t *testing.T) {
 		}
 	})
 }
+
+func TestWithPreStopDelay(t *testing.T) {
+	t.Run("valid delay", func(t *testing.T) {
+		r := &Router{}
+		if err := WithPreStopDelay(5 * time.Second)(r); err != nil {
+			t.Fatalf("WithPreStopDelay(5s) returned error: %v", err)
+		}
+		if r.preStopDelay != 5*time.Second {
+			t.Errorf("expected preStopDelay 5s, got %v", r.preStopDelay)
+		}
+	})
+
+	t.Run("negative delay", func(t *testing.T) {
+		r := &Router{}
+		if err := WithPreStopDelay(-time.Second)(r); !errors.Is(err, ErrorInvalidDelay) {
+			t.Errorf("WithPreStopDelay(-1s) = %v, want ErrorInvalidDelay", err)
+		}
+	})
+}
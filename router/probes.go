@@ -1,25 +1,153 @@
 package router
 
-import "net/http"
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ninlil/butler/clock"
+)
+
+// Reasons used by butler itself when marking the service as not ready
+const (
+	NotReadyManual   = "manual"
+	NotReadyShutdown = "shutdown"
+	NotReadyWorkers  = "workers"
+)
+
+type readinessState struct {
+	mutex   sync.RWMutex
+	reasons map[string]time.Time
+}
 
 var (
-	// Ready is the flag for the readiness-probe
-	Ready bool = true
+	readiness = new(readinessState)
+	healthy   atomic.Bool
+)
+
+var (
+	// Ready is the flag for the readiness-probe, false is read when a router starts serving
+	// and has the same effect as SetReady(false), later changes are ignored
+	//
+	// Deprecated: not safe for concurrent use, use SetReady and IsReady
+	Ready = true
 
-	// Healty is the flag for the liveness-probe
-	Healty bool = true
+	// Healty is the flag for the liveness-probe, false is read when a router starts serving
+	// and has the same effect as SetHealthy(false), later changes are ignored
+	//
+	// Deprecated: not safe for concurrent use, use SetHealthy and IsHealthy
+	Healty = true
 )
 
-func readyProbe(w http.ResponseWriter, r *http.Request) {
+// forwardDeprecatedFlags applies Ready and Healty, when a router starts serving
+func forwardDeprecatedFlags() {
 	if !Ready {
-		w.WriteHeader(http.StatusNotFound)
+		SetReady(false)
+	}
+	if !Healty {
+		SetHealthy(false)
+	}
+}
+
+func init() {
+	healthy.Store(true)
+}
+
+// SetReady sets (or clears) the manual not-ready flag for the readiness-probe
+func SetReady(flag bool) {
+	if flag {
+		ClearNotReady(NotReadyManual)
 	} else {
-		w.WriteHeader(http.StatusOK)
+		SetNotReady(NotReadyManual)
+	}
+}
+
+// SetNotReady marks the service as not ready for the given reason.
+// The readiness-probe fails as long as at least one reason is active.
+func SetNotReady(reason string) {
+	readiness.mutex.Lock()
+	defer readiness.mutex.Unlock()
+
+	if readiness.reasons == nil {
+		readiness.reasons = make(map[string]time.Time)
+	}
+	if _, found := readiness.reasons[reason]; !found {
+		readiness.reasons[reason] = clock.Now()
+	}
+}
+
+// ClearNotReady removes a reason previously set with SetNotReady
+func ClearNotReady(reason string) {
+	readiness.mutex.Lock()
+	defer readiness.mutex.Unlock()
+
+	delete(readiness.reasons, reason)
+}
+
+// IsReady returns true if there are no active not-ready reasons
+func IsReady() bool {
+	readiness.mutex.RLock()
+	defer readiness.mutex.RUnlock()
+
+	return len(readiness.reasons) == 0
+}
+
+// NotReadyReasons returns the active not-ready reasons in sorted order
+func NotReadyReasons() []string {
+	readiness.mutex.RLock()
+	defer readiness.mutex.RUnlock()
+
+	reasons := make([]string, 0, len(readiness.reasons))
+	for reason := range readiness.reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+// notReadySince returns when the reason was set, or the zero time if it isn't active
+func notReadySince(reason string) time.Time {
+	readiness.mutex.RLock()
+	defer readiness.mutex.RUnlock()
+
+	return readiness.reasons[reason]
+}
+
+// SetHealthy sets the flag for the liveness-probe
+func SetHealthy(flag bool) {
+	healthy.Store(flag)
+}
+
+// IsHealthy returns the flag for the liveness-probe
+func IsHealthy() bool {
+	return healthy.Load()
+}
+
+// readyProbe answers the readiness-probe, listing the active reasons and the load of any concurrency-limits
+func (table *routeTable) readyProbe(w http.ResponseWriter, r *http.Request) {
+	lines := NotReadyReasons()
+	status := http.StatusOK
+	if len(lines) > 0 {
+		status = http.StatusNotFound
+	}
+	for _, limiter := range table.limiters {
+		lines = append(lines, limiter.report())
+	}
+
+	if len(lines) > 0 {
+		w.Header().Set("Content-Type", ctTEXT)
+	}
+	w.WriteHeader(status)
+	if len(lines) > 0 {
+		_, _ = w.Write([]byte(strings.Join(lines, "\n")))
 	}
 }
 
 func healthyProbe(w http.ResponseWriter, r *http.Request) {
-	if !Healty {
+	if !IsHealthy() {
 		w.WriteHeader(http.StatusNotFound)
 	} else {
 		w.WriteHeader(http.StatusOK)
@@ -1,9 +1,13 @@
 package router
 
 import (
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestReadyProbe(t *testing.T) {
@@ -12,18 +16,18 @@ func TestReadyProbe(t *testing.T) {
 		ready      bool
 		wantStatus int
 	}{
-		{"Ready=true returns 200", true, http.StatusOK},
-		{"Ready=false returns 404", false, http.StatusNotFound},
+		{"SetReady(true) returns 200", true, http.StatusOK},
+		{"SetReady(false) returns 404", false, http.StatusNotFound},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			Ready = tc.ready
-			t.Cleanup(func() { Ready = true })
+			SetReady(tc.ready)
+			t.Cleanup(func() { SetReady(true) })
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest("GET", "/", nil)
-			readyProbe(w, r)
+			new(routeTable).readyProbe(w, r)
 
 			if w.Code != tc.wantStatus {
 				t.Errorf("status = %d, want %d", w.Code, tc.wantStatus)
@@ -32,20 +36,115 @@ func TestReadyProbe(t *testing.T) {
 	}
 }
 
+func TestNotReadyReasons(t *testing.T) {
+	t.Cleanup(func() {
+		ClearNotReady(NotReadyWorkers)
+		ClearNotReady(NotReadyShutdown)
+	})
+
+	SetNotReady(NotReadyWorkers)
+	SetNotReady(NotReadyShutdown)
+	if IsReady() {
+		t.Fatal("IsReady() = true with active reasons")
+	}
+	if got := strings.Join(NotReadyReasons(), ","); got != "shutdown,workers" {
+		t.Errorf("NotReadyReasons() = %q, want %q", got, "shutdown,workers")
+	}
+
+	w := httptest.NewRecorder()
+	new(routeTable).readyProbe(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if !strings.Contains(w.Body.String(), "workers") {
+		t.Errorf("body = %q, want it to list the reasons", w.Body.String())
+	}
+
+	ClearNotReady(NotReadyWorkers)
+	if IsReady() {
+		t.Error("IsReady() = true while 'shutdown' is still active")
+	}
+	ClearNotReady(NotReadyShutdown)
+	if !IsReady() {
+		t.Errorf("IsReady() = false, reasons: %v", NotReadyReasons())
+	}
+}
+
+func TestShutdownSetsNotReady(t *testing.T) {
+	t.Cleanup(func() { ClearNotReady(NotReadyShutdown) })
+
+	r, err := New(nil, WithPort(9999), WithPreStopDelay(0))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	r.server = &http.Server{Addr: ":0"}
+	r.Shutdown()
+
+	if IsReady() {
+		t.Error("IsReady() = true after Shutdown")
+	}
+}
+
+func TestServeClearsShutdown(t *testing.T) {
+	SetNotReady(NotReadyShutdown)
+	t.Cleanup(func() { ClearNotReady(NotReadyShutdown) })
+
+	r, err := New(nil, WithName("served-again"), WithPreStopDelay(0))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- r.serve(ln) }()
+
+	for deadline := time.Now().Add(time.Second); !IsReady(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("still not ready after Serve: %v", NotReadyReasons())
+		}
+	}
+	r.Shutdown()
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("serve: %v", err)
+	}
+}
+
+func TestDeprecatedFlags(t *testing.T) {
+	t.Cleanup(func() {
+		Ready, Healty = true, true
+		SetReady(true)
+		SetHealthy(true)
+	})
+
+	Ready, Healty = false, false
+	if !IsReady() || !IsHealthy() {
+		t.Error("the flags should only be read when serving")
+	}
+	forwardDeprecatedFlags()
+	if IsReady() || IsHealthy() {
+		t.Error("Ready/Healty = false should fail the probes")
+	}
+	if reasons := NotReadyReasons(); len(reasons) != 1 || reasons[0] != NotReadyManual {
+		t.Errorf("reasons = %v", reasons)
+	}
+}
+
 func TestHealthyProbe(t *testing.T) {
 	tests := []struct {
 		name       string
 		healthy    bool
 		wantStatus int
 	}{
-		{"Healty=true returns 200", true, http.StatusOK},
-		{"Healty=false returns 404", false, http.StatusNotFound},
+		{"SetHealthy(true) returns 200", true, http.StatusOK},
+		{"SetHealthy(false) returns 404", false, http.StatusNotFound},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			Healty = tc.healthy
-			t.Cleanup(func() { Healty = true })
+			SetHealthy(tc.healthy)
+			t.Cleanup(func() { SetHealthy(true) })
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest("GET", "/", nil)
@@ -0,0 +1,324 @@
+package router
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ninlil/butler/clock"
+	"github.com/ninlil/butler/log"
+)
+
+const (
+	hdrRateLimitLimit     = "RateLimit-Limit"
+	hdrRateLimitRemaining = "RateLimit-Remaining"
+	hdrRateLimitReset     = "RateLimit-Reset"
+	hdrRetryAfter         = "Retry-After"
+)
+
+// RateLimitKey returns the key a request is limited by, an empty key is not limited
+type RateLimitKey func(r *http.Request) string
+
+// KeyByIP limits each client-ip (see WithTrustedProxies) separately, this is the default
+func KeyByIP() RateLimitKey {
+	return func(r *http.Request) string {
+		return clientFromRequest(r).IP
+	}
+}
+
+// KeyByHeader limits each value of the header (ex "X-Api-Key") separately,
+// requests without the header are limited by the client-ip
+func KeyByHeader(name string) RateLimitKey {
+	return func(r *http.Request) string {
+		if value := r.Header.Get(name); value != "" {
+			return name + ":" + value
+		}
+		return clientFromRequest(r).IP
+	}
+}
+
+// RateLimitResult is the outcome of a single request against a RateLimitStore
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int           // requests left in the current window
+	Reset     time.Duration // until the limit is fully restored, or (when not allowed) until the next request is
+}
+
+// RateLimitStore keeps the state of the limits, implement it to share the limits between instances (ex in Redis)
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// RateLimitConfig is the rate-limiting configuration for a router (see WithRateLimit) or a single route
+type RateLimitConfig struct {
+	// Limit is the number of requests allowed per Window
+	Limit int
+
+	// Window is the period of the limit (default 1 minute)
+	Window time.Duration
+
+	// Key selects what the limit applies to (default KeyByIP)
+	Key RateLimitKey
+
+	// Store keeps the state of the limits (default NewTokenBucketStore)
+	Store RateLimitStore
+
+	// Disabled turns rate-limiting off, used to exclude a single route from the router-wide configuration
+	Disabled bool
+}
+
+// WithRateLimit limits the requests to all routes, use Route.RateLimit to override for a single route.
+//
+// The router-wide limit is shared by all routes, while a limit on a route only counts the requests to that route.
+func WithRateLimit(config RateLimitConfig) Option {
+	return func(r *Router) error {
+		limiter, err := newRateLimiter(&config, "")
+		if err != nil {
+			return err
+		}
+		r.rateLimit = limiter
+		return nil
+	}
+}
+
+// rateLimiter is a RateLimitConfig with its defaults applied
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	key    RateLimitKey
+	store  RateLimitStore
+	prefix string // separates the keys of routes sharing a store
+}
+
+func newRateLimiter(cfg *RateLimitConfig, prefix string) (*rateLimiter, error) {
+	if cfg == nil || cfg.Disabled {
+		return nil, nil
+	}
+	if cfg.Limit <= 0 || cfg.Window < 0 {
+		return nil, ErrorInvalidRateLimit
+	}
+	rl := &rateLimiter{
+		limit:  cfg.Limit,
+		window: cfg.Window,
+		key:    cfg.Key,
+		store:  cfg.Store,
+		prefix: prefix,
+	}
+	if rl.window == 0 {
+		rl.window = time.Minute
+	}
+	if rl.key == nil {
+		rl.key = KeyByIP()
+	}
+	if rl.store == nil {
+		rl.store = NewTokenBucketStore()
+	}
+	return rl, nil
+}
+
+// initRateLimit sets up the limiter of the route, when it has a configuration of its own
+func (rt *Route) initRateLimit() (err error) {
+	if rt.RateLimit != nil {
+		rt.rateLimit, err = newRateLimiter(rt.RateLimit, "route:"+rt.id()+":")
+	}
+	return err
+}
+
+// rateLimiter returns the active limiter for the route, or nil if none
+func (rt *Route) rateLimiter() *rateLimiter {
+	if rt.RateLimit != nil {
+		return rt.rateLimit
+	}
+	if rt.router != nil {
+		return rt.router.rateLimit
+	}
+	return nil
+}
+
+// handler is the middleware limiting the requests of a route
+func (rl *rateLimiter) handler(rt *Route) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := rl.key(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := rl.store.Allow(r.Context(), rl.prefix+key, rl.limit, rl.window)
+			if err != nil {
+				// a failing store should not take the service down
+				log.FromCtx(r.Context()).Warn().Msgf("router: rate-limit store: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set(hdrRateLimitLimit, strconv.Itoa(rl.limit))
+			h.Set(hdrRateLimitRemaining, strconv.Itoa(max(res.Remaining, 0)))
+			h.Set(hdrRateLimitReset, seconds(res.Reset))
+
+			if !res.Allowed {
+				h.Set(hdrRetryAfter, seconds(res.Reset))
+				rt.writeError(ErrTooManyRequests, w, r, http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds formats a duration as whole seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// memoryStore is the shared part of the in-memory stores, removing idle keys once per window
+type memoryStore[T any] struct {
+	mutex     sync.Mutex
+	entries   map[string]*T
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func (ms *memoryStore[T]) entry(key string, now time.Time, every time.Duration, idle func(*T) bool) *T {
+	if ms.entries == nil {
+		ms.entries = make(map[string]*T)
+		ms.lastSweep = now
+	}
+	if now.Sub(ms.lastSweep) >= every {
+		for k, e := range ms.entries {
+			if idle(e) {
+				delete(ms.entries, k)
+			}
+		}
+		ms.lastSweep = now
+	}
+	e, found := ms.entries[key]
+	if !found {
+		e = new(T)
+		ms.entries[key] = e
+	}
+	return e
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucketStore is an in-memory RateLimitStore using token-buckets, allowing bursts
+// of up to 'limit' requests and refilling at 'limit' per window
+type TokenBucketStore struct {
+	ms memoryStore[bucket]
+}
+
+// NewTokenBucketStore creates an in-memory token-bucket store
+func NewTokenBucketStore() *TokenBucketStore {
+	return &TokenBucketStore{ms: memoryStore[bucket]{now: clock.Now}}
+}
+
+// Allow takes a token from the bucket of the key
+func (s *TokenBucketStore) Allow(_ context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	s.ms.mutex.Lock()
+	defer s.ms.mutex.Unlock()
+
+	now := s.ms.now()
+	rate := float64(limit) / window.Seconds() // tokens per second
+	b := s.ms.entry(key, now, window, func(b *bucket) bool {
+		return now.Sub(b.last) >= window
+	})
+
+	if b.last.IsZero() {
+		b.tokens = float64(limit)
+	} else {
+		b.tokens = min(float64(limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+
+	var res RateLimitResult
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+		res.Reset = time.Duration((float64(limit) - b.tokens) / rate * float64(time.Second))
+	} else {
+		res.Reset = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	res.Remaining = int(b.tokens)
+	return res, nil
+}
+
+type slidingWindow struct {
+	start    time.Time // of the current window
+	current  int
+	previous int
+}
+
+// SlidingWindowStore is an in-memory RateLimitStore using sliding windows, where the count of the
+// previous window is weighted by how much of it still overlaps
+type SlidingWindowStore struct {
+	ms memoryStore[slidingWindow]
+}
+
+// NewSlidingWindowStore creates an in-memory sliding-window store
+func NewSlidingWindowStore() *SlidingWindowStore {
+	return &SlidingWindowStore{ms: memoryStore[slidingWindow]{now: clock.Now}}
+}
+
+// Allow counts the request in the window of the key
+func (s *SlidingWindowStore) Allow(_ context.Context, key string, limit int, size time.Duration) (RateLimitResult, error) {
+	s.ms.mutex.Lock()
+	defer s.ms.mutex.Unlock()
+
+	now := s.ms.now()
+	w := s.ms.entry(key, now, size, func(w *slidingWindow) bool {
+		return now.Sub(w.start) >= 2*size
+	})
+
+	start := now.Truncate(size)
+	switch {
+	case w.start.Equal(start):
+	case w.start.Add(size).Equal(start):
+		w.previous, w.current = w.current, 0
+	default:
+		w.previous, w.current = 0, 0
+	}
+	w.start = start
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(size)
+	used := float64(w.previous)*weight + float64(w.current)
+
+	var res RateLimitResult
+	if used+1 <= float64(limit) {
+		w.current++
+		used++
+		res.Allowed = true
+		res.Reset = size - elapsed
+	} else {
+		res.Reset = retryAfter(w.previous, w.current, limit, size, elapsed)
+	}
+	res.Remaining = limit - int(math.Ceil(used))
+	return res, nil
+}
+
+// retryAfter is the time until the weighted count of the previous window has dropped enough
+// to allow another request, or until the next window when the current one is full
+func retryAfter(previous, current, limit int, size, elapsed time.Duration) time.Duration {
+	if current+1 > limit || previous == 0 {
+		// wait for the next window, where this window is the previous one
+		next := size - elapsed
+		if current+1 > limit {
+			// the requests of this window have to age out as well
+			need := float64(current+1-limit) / float64(current)
+			next += time.Duration(need * float64(size))
+		}
+		return next
+	}
+	// previous*(1 - t/size) + current + 1 <= limit  =>  t >= size*(1 - (limit-current-1)/previous)
+	t := time.Duration(float64(size) * (1 - float64(limit-current-1)/float64(previous)))
+	return max(t-elapsed, 1)
+}
@@ -0,0 +1,163 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time      { return c.t }
+func (c *fakeClock) add(d time.Duration) { c.t = c.t.Add(d) }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func allowN(t *testing.T, s RateLimitStore, n int) (last RateLimitResult) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		last, _ = s.Allow(context.Background(), "k", 3, time.Minute)
+	}
+	return last
+}
+
+func TestTokenBucketStore(t *testing.T) {
+	clock := newFakeClock()
+	s := NewTokenBucketStore()
+	s.ms.now = clock.now
+
+	if res := allowN(t, s, 3); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("third request: %+v, want allowed with 0 remaining", res)
+	}
+	res := allowN(t, s, 1)
+	if res.Allowed || res.Reset != 20*time.Second {
+		t.Fatalf("fourth request: %+v, want denied with reset 20s", res)
+	}
+
+	clock.add(20 * time.Second)
+	if res := allowN(t, s, 1); !res.Allowed {
+		t.Errorf("after refill: %+v, want allowed", res)
+	}
+
+	clock.add(2 * time.Minute)
+	allowN(t, s, 1)
+	if _, found := s.ms.entries["k"]; !found || len(s.ms.entries) != 1 {
+		t.Errorf("entries = %v, want only the active key", s.ms.entries)
+	}
+}
+
+func TestSlidingWindowStore(t *testing.T) {
+	clock := newFakeClock()
+	s := NewSlidingWindowStore()
+	s.ms.now = clock.now
+
+	clock.add(30 * time.Second)
+	if res := allowN(t, s, 3); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("third request: %+v, want allowed with 0 remaining", res)
+	}
+	res := allowN(t, s, 1)
+	if res.Allowed || res.Reset != 50*time.Second {
+		t.Fatalf("fourth request: %+v, want denied with reset 50s", res)
+	}
+
+	// 20s into the next window, two thirds of the previous count remains
+	clock.add(res.Reset)
+	if res := allowN(t, s, 1); !res.Allowed {
+		t.Fatalf("after reset: %+v, want allowed", res)
+	}
+	if res := allowN(t, s, 1); res.Allowed || res.Reset != 20*time.Second {
+		t.Errorf("next request: %+v, want denied with reset 20s", res)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Allow(context.Context, string, int, time.Duration) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store is down")
+}
+
+func TestRateLimit(t *testing.T) {
+	routes := []Route{
+		{Name: "a", Method: "GET", Path: "/a", Handler: handlerReturnStruct},
+		{Name: "b", Method: "GET", Path: "/b", Handler: handlerReturnStruct},
+		{Name: "own", Method: "GET", Path: "/own", Handler: handlerReturnStruct,
+			RateLimit: &RateLimitConfig{Limit: 1, Key: KeyByHeader("X-Api-Key")}},
+		{Name: "free", Method: "GET", Path: "/free", Handler: handlerReturnStruct,
+			RateLimit: &RateLimitConfig{Disabled: true}},
+		{Name: "failing", Method: "GET", Path: "/failing", Handler: handlerReturnStruct,
+			RateLimit: &RateLimitConfig{Limit: 1, Store: failingStore{}}},
+	}
+	h := buildTestHandlerWithOpts(t, routes, WithRateLimit(RateLimitConfig{Limit: 2, Window: time.Hour}))
+
+	serve := func(path, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		if key != "" {
+			req.Header.Set("X-Api-Key", key)
+		}
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	// the router-wide limit is shared between the routes
+	w := serve("/a", "")
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("first request: %d %v", w.Code, w.Header())
+	}
+	serve("/b", "")
+	w = serve("/a", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: status %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1800" || w.Body.String() != `{"error":"too many requests"}` {
+		t.Errorf("third request: Retry-After %q, body %q", w.Header().Get("Retry-After"), w.Body.String())
+	}
+
+	// a route with a limit of its own is counted separately, by the key
+	if w := serve("/own", "k1"); w.Code != http.StatusOK {
+		t.Errorf("own k1: status %d, want 200", w.Code)
+	}
+	if w := serve("/own", "k1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("own k1 again: status %d, want 429", w.Code)
+	}
+	if w := serve("/own", "k2"); w.Code != http.StatusOK {
+		t.Errorf("own k2: status %d, want 200", w.Code)
+	}
+
+	if w := serve("/free", ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("disabled: status %d, headers %v", w.Code, w.Header())
+	}
+	if w := serve("/failing", ""); w.Code != http.StatusOK {
+		t.Errorf("failing store: status %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitUnnamedRoutes(t *testing.T) {
+	store := NewTokenBucketStore()
+	limit := &RateLimitConfig{Limit: 1, Store: store}
+	routes := []Route{
+		{Method: "GET", Path: "/x", Handler: handlerReturnStruct, RateLimit: limit},
+		{Method: "GET", Path: "/y", Handler: handlerReturnStruct, RateLimit: limit},
+	}
+	h := buildTestHandler(t, routes)
+
+	// unnamed routes sharing a store are still counted separately
+	for _, path := range []string{"/x", "/y"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d, want 200", path, w.Code)
+		}
+	}
+}
+
+func TestWithRateLimitInvalid(t *testing.T) {
+	if _, err := New(nil, WithRateLimit(RateLimitConfig{})); !errors.Is(err, ErrorInvalidRateLimit) {
+		t.Errorf("err = %v, want %v", err, ErrorInvalidRateLimit)
+	}
+}
@@ -0,0 +1,298 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ninlil/butler/bufferedresponse"
+	"github.com/ninlil/butler/clock"
+	"github.com/ninlil/butler/log"
+)
+
+const (
+	defaultRecordBodySize = 64 * 1024
+
+	// Redacted replaces the values of redacted headers in recordings
+	Redacted = "[REDACTED]"
+)
+
+// DefaultRedactedHeaders are the headers redacted when RecorderConfig.Redact isn't set
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Recording is a recorded request and its response
+type Recording struct {
+	Time     time.Time        `json:"time"`
+	Route    string           `json:"route,omitempty"`
+	Duration float64          `json:"duration"` // in milliseconds
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request of a recording
+type RecordedRequest struct {
+	Method    string      `json:"method"`
+	URL       string      `json:"url"` // path and query
+	Header    http.Header `json:"header,omitempty"`
+	Body      []byte      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// RecordedResponse is the response of a recording, before any compression
+type RecordedResponse struct {
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header,omitempty"`
+	Body      []byte      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// RecordSink receives the recordings, see NewRecordWriter and NewRecordRing
+type RecordSink interface {
+	Record(rec *Recording) error
+}
+
+// RecorderConfig is the configuration of the request/response-recorder (see WithRecorder)
+type RecorderConfig struct {
+	// Sink receives the recordings (required)
+	Sink RecordSink
+
+	// Sample records only every n:th request, 0 or 1 records all
+	Sample int
+
+	// Routes are the names of the routes to record (default all, including unmatched requests)
+	Routes []string
+
+	// Redact are the headers (of both requests and responses) to redact, a trailing "*" matches any suffix
+	// (default DefaultRedactedHeaders)
+	Redact []string
+
+	// MaxBodySize is the max size of the recorded bodies, larger bodies are truncated (default 64 KiB)
+	MaxBodySize int
+}
+
+// WithRecorder records requests and their responses, for debugging and replay (see package replay)
+func WithRecorder(config RecorderConfig) Option {
+	return func(r *Router) error {
+		if config.Sink == nil || config.Sample < 0 || config.MaxBodySize < 0 {
+			return ErrorInvalidRecorder
+		}
+		if config.Redact == nil {
+			config.Redact = DefaultRedactedHeaders
+		}
+		if config.MaxBodySize == 0 {
+			config.MaxBodySize = defaultRecordBodySize
+		}
+		r.recorder = &recorder{RecorderConfig: config}
+		return nil
+	}
+}
+
+type recorder struct {
+	RecorderConfig
+	seen atomic.Uint64
+}
+
+// redact returns a copy of the headers, with the redacted values replaced
+func (rec *recorder) redact(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	h = h.Clone()
+	for name, values := range h {
+		for _, pattern := range rec.Redact {
+			prefix, wildcard := strings.CutSuffix(pattern, "*")
+			if (wildcard && strings.HasPrefix(name, http.CanonicalHeaderKey(prefix))) || name == http.CanonicalHeaderKey(pattern) {
+				for i := range values {
+					values[i] = Redacted
+				}
+			}
+		}
+	}
+	return h
+}
+
+func (rec *recorder) truncate(body []byte) ([]byte, bool) {
+	if len(body) > rec.MaxBodySize {
+		return slices.Clone(body[:rec.MaxBodySize]), true
+	}
+	return slices.Clone(body), false
+}
+
+// handler is the middleware recording the requests
+func (rec *recorder) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w2, ok := bufferedresponse.Get(w)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var name string
+		if route := RouteFromRequest(r); route != nil {
+			name = route.Name
+		}
+		if len(rec.Routes) > 0 && !slices.Contains(rec.Routes, name) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if n := rec.seen.Add(1); rec.Sample > 1 && (n-1)%uint64(rec.Sample) != 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recording := &Recording{
+			Time:  clock.Now(),
+			Route: name,
+			Request: RecordedRequest{
+				Method: r.Method,
+				URL:    r.URL.RequestURI(),
+				Header: rec.redact(r.Header),
+			},
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			// read one byte more than kept, to know if the body is truncated
+			buf, err := io.ReadAll(io.LimitReader(r.Body, int64(rec.MaxBodySize)+1))
+			recording.Request.Body, recording.Request.Truncated = rec.truncate(buf)
+			body := io.MultiReader(bytes.NewReader(buf), r.Body)
+			if err != nil {
+				body = io.MultiReader(bytes.NewReader(buf), errReader{err})
+			}
+			r.Body = readCloser{Reader: body, Closer: r.Body}
+		}
+
+		next.ServeHTTP(w, r)
+
+		recording.Duration = float64(clock.Since(recording.Time)) / float64(time.Millisecond)
+		recording.Response.Status = w2.Status()
+		recording.Response.Header = rec.redact(w.Header())
+		recording.Response.Body, recording.Response.Truncated = rec.truncate(w2.Bytes())
+		if err := rec.Sink.Record(recording); err != nil {
+			log.FromCtx(r.Context()).Warn().Msgf("router: unable to record the request: %v", err)
+		}
+	})
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type errReader struct{ err error }
+
+func (er errReader) Read([]byte) (int, error) { return 0, er.err }
+
+// RecordWriter writes recordings as JSON-lines
+type RecordWriter struct {
+	mutex sync.Mutex
+	w     io.Writer
+	enc   *json.Encoder
+}
+
+// NewRecordWriter creates a sink writing to w
+func NewRecordWriter(w io.Writer) *RecordWriter {
+	return &RecordWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+// OpenRecordFile creates a sink appending to a file
+func OpenRecordFile(path string) (*RecordWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecordWriter(f), nil
+}
+
+// Record writes a recording as a single line
+func (rw *RecordWriter) Record(rec *Recording) error {
+	rw.mutex.Lock()
+	defer rw.mutex.Unlock()
+	return rw.enc.Encode(rec)
+}
+
+// Close closes the underlying writer, if it's an io.Closer
+func (rw *RecordWriter) Close() error {
+	if c, ok := rw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// RecordRing keeps the latest recordings in memory
+type RecordRing struct {
+	mutex sync.Mutex
+	recs  []Recording
+	next  int
+	full  bool
+}
+
+// NewRecordRing creates a sink keeping the latest 'size' recordings
+func NewRecordRing(size int) *RecordRing {
+	return &RecordRing{recs: make([]Recording, max(size, 1))}
+}
+
+// Record adds a recording, replacing the oldest when full
+func (ring *RecordRing) Record(rec *Recording) error {
+	ring.mutex.Lock()
+	defer ring.mutex.Unlock()
+
+	ring.recs[ring.next] = *rec
+	ring.next = (ring.next + 1) % len(ring.recs)
+	ring.full = ring.full || ring.next == 0
+	return nil
+}
+
+// Recordings returns the kept recordings, oldest first
+func (ring *RecordRing) Recordings() []Recording {
+	ring.mutex.Lock()
+	defer ring.mutex.Unlock()
+
+	if !ring.full {
+		return slices.Clone(ring.recs[:ring.next])
+	}
+	return append(slices.Clone(ring.recs[ring.next:]), ring.recs[:ring.next]...)
+}
+
+// WriteTo writes the kept recordings as JSON-lines, ex from a debug-endpoint
+func (ring *RecordRing) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	rw := NewRecordWriter(cw)
+	for _, rec := range ring.Recordings() {
+		if err := rw.Record(&rec); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// ReadRecordings reads recordings written as JSON-lines
+func ReadRecordings(r io.Reader) ([]Recording, error) {
+	var recs []Recording
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var rec Recording
+		if err := dec.Decode(&rec); err == io.EOF {
+			return recs, nil
+		} else if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+}
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	ring := NewRecordRing(10)
+	routes := []Route{
+		{Name: "create", Method: "POST", Path: "/items", Handler: handlerBody},
+		{Name: "other", Method: "GET", Path: "/other", Handler: handlerReturnStruct},
+	}
+	h := buildTestHandlerWithOpts(t, routes, WithRecorder(RecorderConfig{
+		Sink:        ring,
+		Routes:      []string{"create"},
+		Redact:      []string{"Authorization", "X-Secret-*"},
+		MaxBodySize: 20,
+	}))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/items?x=1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("X-Secret-Token", "secret")
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"id":1,"name":"a"}`)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/other", nil))
+	long := post(`{"id":2,"name":"a longer name"}`)
+	if long.Code != http.StatusOK || !strings.Contains(long.Body.String(), "a longer name") {
+		t.Fatalf("the handler didn't get the whole body: %d %s", long.Code, long.Body.String())
+	}
+
+	recs := ring.Recordings()
+	if len(recs) != 2 {
+		t.Fatalf("got %d recordings, want 2", len(recs))
+	}
+	rec := recs[0]
+	if rec.Route != "create" || rec.Request.Method != "POST" || rec.Request.URL != "/items?x=1" ||
+		string(rec.Request.Body) != `{"id":1,"name":"a"}` || rec.Request.Truncated {
+		t.Errorf("request = %+v", rec.Request)
+	}
+	if rec.Request.Header.Get("Authorization") != Redacted || rec.Request.Header.Get("X-Secret-Token") != Redacted ||
+		rec.Request.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("request headers = %v", rec.Request.Header)
+	}
+	if rec.Response.Status != http.StatusOK || string(rec.Response.Body) != w.Body.String() ||
+		rec.Response.Header.Get("Content-Type") == "" {
+		t.Errorf("response = %+v", rec.Response)
+	}
+	if !recs[1].Request.Truncated || len(recs[1].Request.Body) != 20 || !recs[1].Response.Truncated {
+		t.Errorf("large bodies should be truncated: %+v", recs[1])
+	}
+}
+
+func TestRecorderSample(t *testing.T) {
+	ring := NewRecordRing(10)
+	h := buildTestHandlerWithOpts(t, []Route{{Name: "a", Method: "GET", Path: "/a", Handler: handlerReturnStruct}},
+		WithRecorder(RecorderConfig{Sink: ring, Sample: 3}))
+	for i := 0; i < 7; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a", nil))
+	}
+	if n := len(ring.Recordings()); n != 3 {
+		t.Errorf("got %d recordings, want 3", n)
+	}
+	if _, err := New(nil, WithRecorder(RecorderConfig{})); err != ErrorInvalidRecorder {
+		t.Errorf("err = %v, want %v", err, ErrorInvalidRecorder)
+	}
+}
+
+func TestRecordRingAndWriter(t *testing.T) {
+	ring := NewRecordRing(2)
+	for _, url := range []string{"/1", "/2", "/3"} {
+		_ = ring.Record(&Recording{Request: RecordedRequest{Method: "GET", URL: url}})
+	}
+
+	var buf bytes.Buffer
+	if _, err := ring.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	recs, err := ReadRecordings(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || recs[0].Request.URL != "/2" || recs[1].Request.URL != "/3" {
+		t.Errorf("got %+v", recs)
+	}
+}
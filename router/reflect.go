@@ -3,6 +3,7 @@ package router
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"reflect"
 	"regexp"
 
+	"github.com/ninlil/butler/auth"
 	"github.com/ninlil/butler/log"
 )
 
@@ -29,36 +31,59 @@ func (rt *Route) createArgs(w http.ResponseWriter, r *http.Request) ([]reflect.V
 
 		// log.Debug().Msgf("router: arg #%d is a %s", i, arg.Kind())
 
-		switch true {
-		case arg == tContext:
-			args[i] = reflect.ValueOf(r.Context())
+		v, err := rt.createArg(arg, w, r)
+		if err != nil {
+			return nil, err
+		}
+		if !v.IsValid() {
+			log.Warn().Msgf("router: route %s: arg %d is of unknown type: %v", rt.Name, i, arg.String())
+		}
+		args[i] = v
+	}
 
-		case arg == tResponseWriter:
-			args[i] = reflect.ValueOf(w)
+	return args, nil
+}
 
-		case arg == tRequest:
-			args[i] = reflect.ValueOf(r)
+// createArg creates the value of a single argument, the value is invalid if the type is unknown
+func (rt *Route) createArg(arg reflect.Type, w http.ResponseWriter, r *http.Request) (reflect.Value, error) {
+	switch true {
+	case arg == tContext:
+		return reflect.ValueOf(r.Context()), nil
 
-		case arg.Kind() == reflect.Struct:
-			ptr, err := rt.createStruct(arg, r)
-			if err != nil {
-				return nil, err
-			}
-			args[i] = ptr.Elem()
+	case arg == tResponseWriter:
+		return reflect.ValueOf(w), nil
 
-		case arg.Kind() == reflect.Ptr && arg.Elem().Kind() == reflect.Struct:
-			ptr, err := rt.createStruct(arg.Elem(), r)
-			if err != nil {
-				return nil, err
-			}
-			args[i] = ptr
+	case arg == tRequest:
+		return reflect.ValueOf(r), nil
 
-		default:
-			log.Warn().Msgf("router: route %s: arg %d is of unknown type: %v", rt.Name, i, arg.String())
+	case arg == tClientInfo:
+		return reflect.ValueOf(clientFromRequest(r)), nil
+
+	case arg.Kind() == reflect.Ptr && arg.Elem() == tClientInfo:
+		ci := clientFromRequest(r)
+		return reflect.ValueOf(&ci), nil
+
+	case arg == tPrincipal:
+		var p auth.Principal
+		if ptr := principalFromRequest(r); ptr != nil {
+			p = *ptr
 		}
-	}
+		return reflect.ValueOf(p), nil
 
-	return args, nil
+	case arg.Kind() == reflect.Ptr && arg.Elem() == tPrincipal:
+		return reflect.ValueOf(principalFromRequest(r)), nil
+
+	case arg.Kind() == reflect.Struct:
+		ptr, err := rt.createStruct(arg, r)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return ptr.Elem(), nil
+
+	case arg.Kind() == reflect.Ptr && arg.Elem().Kind() == reflect.Struct:
+		return rt.createStruct(arg.Elem(), r)
+	}
+	return reflect.Value{}, nil
 }
 
 func (param *paramData) getValue(f reflect.Value, tags *tagInfo, r *http.Request) (value string, found bool, handled bool, err error) {
@@ -192,6 +217,9 @@ func (param *paramData) fillField(i int, r *http.Request) error {
 
 	value, found, handled, err := param.getValue(f, tags, r)
 	if handled || err != nil {
+		if errors.Is(err, ErrBodyTooLarge) {
+			return err
+		}
 		if err != nil {
 			err = newFieldError(err, tags.Name, value, err.Error())
 		}
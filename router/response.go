@@ -11,10 +11,17 @@ import (
 
 	"github.com/ninlil/butler/bufferedresponse"
 	"github.com/ninlil/butler/log"
+	"github.com/ninlil/butler/tracing"
 )
 
 type ctFormat int
 
+// errorResult is the body written by writeError
+type errorResult struct {
+	XMLName xml.Name    `json:"-" xml:"result"`
+	Error   interface{} `json:"error" xml:"error"`
+}
+
 const (
 	ctfJSON ctFormat = iota
 	ctfXML
@@ -108,6 +115,36 @@ func createResponse(accept string, data interface{}, url string) (buf []byte, ct
 	return
 }
 
+// Marshal encodes data the same way as a route-response, using the media-type (as in an
+// "Accept" header) to select the format. The Content-Type of the encoded data is returned.
+func Marshal(mediaType string, data interface{}) (buf []byte, contentType string, err error) {
+	buf, contentType, indent, err := createResponse(mediaType, data, "")
+	if indent > 0 {
+		contentType += fmt.Sprintf("; indent=%d", indent)
+	}
+	return buf, contentType, err
+}
+
+// Unmarshal decodes a body the same way as a route-request, using the "Content-Type" to select the format.
+// Any body can be decoded into a *[]byte, and text-bodies into a *string.
+func Unmarshal(contentType string, buf []byte, dest interface{}) error {
+	ctf, _, isCustom := getContentTypeFormat(contentType, "body", "")
+	switch d := dest.(type) {
+	case *[]byte:
+		*d = buf
+		return nil
+	case *string:
+		if !isCustom || ctf == ctfTEXT {
+			*d = string(buf)
+			return nil
+		}
+	}
+	if err := ctf.Unmarshal(buf, dest); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnmarshal(ctf), err)
+	}
+	return nil
+}
+
 func (rt *Route) writeResponse(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
 
 	var w2 *bufferedresponse.ResponseWriter = nil
@@ -115,9 +152,18 @@ func (rt *Route) writeResponse(w http.ResponseWriter, r *http.Request, status in
 		w2, _ = bufferedresponse.Get(w)
 	}
 
+	_, span := tracing.Start(r.Context(), "serialize")
 	buf, ct, indent, err := createResponse(r.Header.Get("Accept"), data, r.URL.String())
+	span.RecordError(err)
+	span.End()
 
 	if err != nil {
+		if _, isError := data.(*errorResult); isError {
+			// unable to encode an error, don't try again
+			log.FromCtx(r.Context()).Error().Msgf("router: unable to write error: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 		rt.writeError(err, w, r, http.StatusInternalServerError)
 		return
 	}
@@ -139,6 +185,9 @@ func (rt *Route) writeResponse(w http.ResponseWriter, r *http.Request, status in
 		w.Header().Set("Content-Length", fmt.Sprint(size))
 	}
 
+	if status == 0 {
+		status = rt.defaultStatus
+	}
 	if status == 0 {
 		if (size == 0) && !rt.router.skip204 {
 			status = http.StatusNoContent
@@ -147,6 +196,10 @@ func (rt *Route) writeResponse(w http.ResponseWriter, r *http.Request, status in
 		}
 	}
 
+	if rt.Cache != nil && status < http.StatusBadRequest {
+		rt.Cache.writeHeaders(w.Header())
+	}
+
 	w.WriteHeader(status)
 	if len(buf) > 0 {
 		_, _ = w.Write(buf)
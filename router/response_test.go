@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -131,3 +132,35 @@ func TestCreateResponse(t *testing.T) {
 		}
 	})
 }
+
+func TestMarshalUnmarshal(t *testing.T) {
+	type item struct {
+		XMLName xml.Name `json:"-" xml:"item"`
+		Name    string   `json:"name" xml:"name"`
+	}
+
+	for _, media := range []string{"application/json", "application/xml"} {
+		buf, ct, err := Marshal(media, &item{Name: "apple"})
+		if err != nil {
+			t.Fatalf("%s: %v", media, err)
+		}
+		if !strings.HasPrefix(ct, media) {
+			t.Errorf("%s: content-type = %q", media, ct)
+		}
+		var got item
+		if err := Unmarshal(ct, buf, &got); err != nil || got.Name != "apple" {
+			t.Errorf("%s: got %+v, %v", media, got, err)
+		}
+	}
+
+	var s string
+	if err := Unmarshal("text/plain", []byte("hello"), &s); err != nil || s != "hello" {
+		t.Errorf("text: got %q, %v", s, err)
+	}
+
+	var got item
+	var ue ErrUnmarshal
+	if err := Unmarshal("application/json", []byte("{"), &got); !errors.As(err, &ue) {
+		t.Errorf("err = %v, want ErrUnmarshal", err)
+	}
+}
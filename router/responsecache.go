@@ -0,0 +1,274 @@
+package router
+
+import (
+	"container/list"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ninlil/butler/auth"
+	"github.com/ninlil/butler/bufferedresponse"
+	"github.com/ninlil/butler/clock"
+)
+
+// CacheConfig declares how the responses of a route may be cached, in the 'Cache-Control' and 'Vary' headers
+// and by the response-cache of the router (see WithResponseCache)
+type CacheConfig struct {
+	// MaxAge is how long the response is fresh ('max-age')
+	MaxAge time.Duration
+
+	// SharedMaxAge is how long the response is fresh in shared caches ('s-maxage'), and in the response-cache
+	SharedMaxAge time.Duration
+
+	// Public allows any cache to store the response, even if it normally wouldn't ('public')
+	Public bool
+
+	// Private allows only the browser to store the response ('private'), it's not kept in the response-cache
+	Private bool
+
+	// StaleWhileRevalidate is how long a stale response may be used while revalidating ('stale-while-revalidate')
+	StaleWhileRevalidate time.Duration
+
+	// Vary are the request-headers the response depends on (in addition to 'Accept')
+	Vary []string
+}
+
+func (cfg *CacheConfig) validate() error {
+	if cfg != nil && (cfg.MaxAge < 0 || cfg.SharedMaxAge < 0 || cfg.StaleWhileRevalidate < 0 || (cfg.Public && cfg.Private)) {
+		return ErrorInvalidCache
+	}
+	return nil
+}
+
+// cacheControl returns the value of the 'Cache-Control' header
+func (cfg *CacheConfig) cacheControl() string {
+	var directives []string
+	switch {
+	case cfg.Private:
+		directives = append(directives, "private")
+	case cfg.Public:
+		directives = append(directives, "public")
+	}
+	if cfg.MaxAge > 0 {
+		directives = append(directives, "max-age="+seconds(cfg.MaxAge))
+	}
+	if cfg.SharedMaxAge > 0 {
+		directives = append(directives, "s-maxage="+seconds(cfg.SharedMaxAge))
+	}
+	if cfg.StaleWhileRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+seconds(cfg.StaleWhileRevalidate))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// writeHeaders adds the caching-headers to a successful response, unless the handler has set them
+func (cfg *CacheConfig) writeHeaders(h http.Header) {
+	if cc := cfg.cacheControl(); cc != "" && h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", cc)
+	}
+	for _, name := range cfg.Vary {
+		h.Add("Vary", http.CanonicalHeaderKey(name))
+	}
+}
+
+// ttl is how long the response is kept in the response-cache, 0 if not at all
+func (cfg *CacheConfig) ttl() time.Duration {
+	if cfg == nil || cfg.Private {
+		return 0
+	}
+	if cfg.SharedMaxAge > 0 {
+		return cfg.SharedMaxAge
+	}
+	return cfg.MaxAge
+}
+
+// WithResponseCache keeps GET-responses of routes with a Route.Cache in memory, serving them without calling
+// the handler while they are fresh. The least recently used responses are dropped to keep within maxEntries
+// and maxBytes (of the bodies, 0 is unlimited).
+func WithResponseCache(maxEntries int, maxBytes int64) Option {
+	return func(r *Router) error {
+		if maxEntries <= 0 || maxBytes < 0 {
+			return ErrorInvalidCache
+		}
+		r.cache = newResponseCache(maxEntries, maxBytes)
+		return nil
+	}
+}
+
+// InvalidateCache drops the cached responses of the named routes
+func (r *Router) InvalidateCache(names ...string) {
+	if r.cache != nil {
+		r.cache.invalidate(func(e *cacheEntry) bool { return slices.Contains(names, e.route) })
+	}
+}
+
+type cacheEntry struct {
+	key     string
+	route   string
+	status  int
+	header  http.Header
+	body    []byte
+	stored  time.Time
+	expires time.Time
+}
+
+// responseCache is an LRU-cache of responses
+type responseCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	size       int64
+	lru        *list.List // of *cacheEntry, most recently used first
+	entries    map[string]*list.Element
+	now        func() time.Time
+}
+
+func newResponseCache(maxEntries int, maxBytes int64) *responseCache {
+	return &responseCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		now:        clock.Now,
+	}
+}
+
+// cacheKey identifies a response by method, path, query, principal and the headers it varies by.
+// Requests with credentials that can't be told apart by a principal aren't cached (false).
+func cacheKey(r *http.Request, vary []string) (string, bool) {
+	var sb strings.Builder
+	sb.WriteString(r.Method + " " + r.URL.Path + "?" + r.URL.Query().Encode())
+	if p, found := auth.PrincipalFromCtx(r.Context()); found {
+		if p == nil || p.Subject == "" {
+			return "", false
+		}
+		// the authorization of the route (ex a Policy) ran for this principal when the response was stored
+		sb.WriteString("\nprincipal: " + p.Method + " " + p.Subject)
+	} else if r.Header.Get("Authorization") != "" {
+		return "", false
+	}
+	for _, name := range append([]string{"Accept"}, vary...) {
+		sb.WriteString("\n" + strings.ToLower(name) + ": " + strings.Join(r.Header.Values(name), ", "))
+	}
+	return sb.String(), true
+}
+
+// get returns a fresh entry
+func (c *responseCache) get(key string) (*cacheEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+// put stores the entry, dropping the least recently used ones to make room
+func (c *responseCache) put(entry *cacheEntry) {
+	size := int64(len(entry.body))
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, found := c.entries[entry.key]; found {
+		c.remove(elem)
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += size
+	for c.lru.Len() > c.maxEntries || (c.maxBytes > 0 && c.size > c.maxBytes) {
+		c.remove(c.lru.Back())
+	}
+}
+
+// invalidate drops the matching entries
+func (c *responseCache) invalidate(match func(*cacheEntry) bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if match(elem.Value.(*cacheEntry)) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// remove drops an entry, the caller must hold the mutex
+func (c *responseCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.body))
+}
+
+// handler is the middleware serving the cached responses of a route, and storing new ones
+func (c *responseCache) handler(rt *Route) func(http.Handler) http.Handler {
+	ttl := rt.Cache.ttl()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w2, ok := bufferedresponse.Get(w)
+			key, cacheable := cacheKey(r, rt.Cache.Vary)
+			if r.Method != http.MethodGet || !ok || !cacheable {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if entry, found := c.get(key); found {
+				for name, values := range entry.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Age", strconv.FormatInt(int64(c.now().Sub(entry.stored)/time.Second), 10))
+				w.WriteHeader(entry.status)
+				_, _ = w.Write(entry.body)
+				return
+			}
+
+			before := w.Header().Clone()
+			next.ServeHTTP(w, r)
+
+			cc := w.Header().Get("Cache-Control")
+			if w2.Status() != http.StatusOK || strings.Contains(cc, "no-store") || strings.Contains(cc, "private") {
+				return
+			}
+			header := headerChanges(before, w.Header())
+			if _, found := header["Set-Cookie"]; found {
+				return // the cookies are for this client only
+			}
+			stored := c.now()
+			c.put(&cacheEntry{
+				key:     key,
+				route:   rt.Name,
+				status:  w2.Status(),
+				header:  header,
+				body:    slices.Clone(w2.Bytes()),
+				stored:  stored,
+				expires: stored.Add(ttl),
+			})
+		})
+	}
+}
+
+// headerChanges returns the headers added or changed by a route, not the ones of the outer middlewares
+func headerChanges(before, after http.Header) http.Header {
+	header := make(http.Header)
+	for name, values := range after {
+		if !slices.Equal(before[name], values) {
+			header[name] = slices.Clone(values)
+		}
+	}
+	return header
+}
@@ -0,0 +1,208 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ninlil/butler/auth"
+)
+
+func TestCacheHeaders(t *testing.T) {
+	routes := []Route{
+		{Name: "public", Method: "GET", Path: "/public", Handler: handlerReturnStruct,
+			Cache: &CacheConfig{Public: true, MaxAge: time.Minute, SharedMaxAge: time.Hour, StaleWhileRevalidate: 30 * time.Second, Vary: []string{"accept-language"}}},
+		{Name: "private", Method: "GET", Path: "/private", Handler: handlerReturnStruct, Cache: &CacheConfig{Private: true, MaxAge: time.Minute}},
+		{Name: "error", Method: "GET", Path: "/error", Handler: handlerReturnError, Cache: &CacheConfig{MaxAge: time.Minute}},
+	}
+	h := buildTestHandler(t, routes)
+
+	tests := []struct {
+		path      string
+		wantCache string
+		wantVary  string
+	}{
+		{"/public", "public, max-age=60, s-maxage=3600, stale-while-revalidate=30", "Accept-Language"},
+		{"/private", "private, max-age=60", ""},
+		{"/error", "", ""},
+	}
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		if got := w.Header().Get("Cache-Control"); got != tc.wantCache {
+			t.Errorf("%s: Cache-Control = %q, want %q", tc.path, got, tc.wantCache)
+		}
+		if got := w.Header().Get("Vary"); got != tc.wantVary {
+			t.Errorf("%s: Vary = %q, want %q", tc.path, got, tc.wantVary)
+		}
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	var calls atomic.Int32
+	counter := func() string {
+		return fmt.Sprint(calls.Add(1))
+	}
+	cached := &CacheConfig{MaxAge: time.Minute, Vary: []string{"X-Tenant"}}
+	session := func(w http.ResponseWriter) string {
+		n := calls.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: fmt.Sprintf("user-%d", n)})
+		return fmt.Sprint(n)
+	}
+	routes := []Route{
+		{Name: "cached", Method: "GET", Path: "/cached", Handler: counter, Cache: cached},
+		{Name: "session", Method: "GET", Path: "/session", Handler: session, Cache: cached},
+		{Name: "private", Method: "GET", Path: "/private", Handler: counter, Cache: &CacheConfig{Private: true, MaxAge: time.Minute}},
+		{Name: "post", Method: "POST", Path: "/cached", Handler: counter, Cache: cached},
+	}
+	h := buildTestHandlerWithOpts(t, routes, WithResponseCache(10, 0))
+
+	get := func(method, path, tenant string) (string, string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Accept", "text/plain")
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: status %d", method, path, w.Code)
+		}
+		return w.Body.String(), w.Header().Get("Age")
+	}
+
+	bodyOf := func(method, path string) string {
+		body, _ := get(method, path, "")
+		return body
+	}
+
+	first, _ := get("GET", "/cached?a=1&b=2", "")
+	if body, age := get("GET", "/cached?b=2&a=1", ""); body != first || age != "0" {
+		t.Errorf("not served from cache: %q (age %q), want %q", body, age, first)
+	}
+	if body, _ := get("GET", "/cached?a=1&b=2", "other"); body == first {
+		t.Error("served from cache for another value of a Vary-header")
+	}
+	if body, _ := get("GET", "/cached", ""); body == first {
+		t.Error("served from cache for another query")
+	}
+	if a, b := bodyOf("POST", "/cached"), bodyOf("POST", "/cached"); a == b {
+		t.Error("POST served from cache")
+	}
+	if a, b := bodyOf("GET", "/private"), bodyOf("GET", "/private"); a == b {
+		t.Error("private response served from cache")
+	}
+
+	if a, b := bodyOf("GET", "/session"), bodyOf("GET", "/session"); a == b {
+		t.Error("response setting a cookie served from cache")
+	}
+
+	h.(*Router).InvalidateCache("cached")
+	if body, _ := get("GET", "/cached?a=1&b=2", ""); body == first {
+		t.Error("served from cache after invalidation")
+	}
+}
+
+func TestResponseCacheLimits(t *testing.T) {
+	c := newResponseCache(2, 10)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	put := func(key string, size int) {
+		c.put(&cacheEntry{key: key, route: key, body: make([]byte, size), stored: now, expires: now.Add(time.Minute)})
+	}
+	put("a", 4)
+	put("b", 4)
+	c.get("a") // a is now the most recently used
+	put("c", 4)
+	if _, found := c.get("b"); found {
+		t.Error("b should be dropped as the least recently used")
+	}
+	if _, found := c.get("a"); !found {
+		t.Error("a should be kept")
+	}
+
+	put("d", 8) // over maxBytes together with the others
+	if _, found := c.get("a"); found || c.size != 8 {
+		t.Errorf("size = %d, with a found = %t", c.size, found)
+	}
+	put("huge", 11)
+	if _, found := c.get("huge"); found {
+		t.Error("entry larger than maxBytes should not be stored")
+	}
+
+	now = now.Add(time.Minute)
+	if _, found := c.get("d"); found {
+		t.Error("expired entry should not be found")
+	}
+}
+
+func TestResponseCachePrincipal(t *testing.T) {
+	keys := &auth.APIKey{Lookup: auth.StaticKeys(map[string]auth.Principal{
+		"a": {Subject: "alice", Claims: map[string]interface{}{"tenant": "A"}},
+		"b": {Subject: "bob", Claims: map[string]interface{}{"tenant": "B"}},
+	})}
+	policy := func(p *auth.Principal) error {
+		if p.Claims["tenant"] != "A" {
+			return fmt.Errorf("%w: wrong tenant", ErrForbidden)
+		}
+		return nil
+	}
+	secret := func(p *auth.Principal) string {
+		return fmt.Sprintf("secret of %s", p.Claims["tenant"])
+	}
+	var calls atomic.Int32
+	counter := func() string {
+		return fmt.Sprint(calls.Add(1))
+	}
+	routes := []Route{
+		{Name: "secret", Method: "GET", Path: "/secret", Handler: secret, Policy: policy,
+			Auth: &AuthConfig{Authenticator: keys}, Cache: &CacheConfig{MaxAge: time.Minute}},
+		{Name: "open", Method: "GET", Path: "/open", Handler: counter, Cache: &CacheConfig{MaxAge: time.Minute}},
+	}
+	h := buildTestHandlerWithOpts(t, routes, WithResponseCache(10, 0))
+
+	get := func(path, key, authorization string) (int, string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", "text/plain")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		h.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	if status, _ := get("/secret", "b", ""); status != http.StatusForbidden {
+		t.Errorf("tenant B: status %d, want 403", status)
+	}
+	if status, body := get("/secret", "a", ""); status != http.StatusOK || body != "secret of A" {
+		t.Errorf("tenant A: %d %q", status, body)
+	}
+	if status, body := get("/secret", "b", ""); status != http.StatusForbidden {
+		t.Errorf("tenant B after A: %d %q, want 403", status, body)
+	}
+
+	// without a principal, requests with credentials are never cached
+	first, _ := get("/open", "", "Bearer one")
+	second, _ := get("/open", "", "Bearer two")
+	if _, body := get("/open", "", "Bearer two"); first != http.StatusOK || second != http.StatusOK || body != "3" {
+		t.Errorf("with Authorization: body %q, want 3 calls", body)
+	}
+}
+
+func TestInvalidCache(t *testing.T) {
+	if _, err := New(nil, WithResponseCache(0, 0)); err != ErrorInvalidCache {
+		t.Errorf("err = %v, want %v", err, ErrorInvalidCache)
+	}
+	rt := Route{Method: "GET", Path: "/x", Handler: handlerReturnStruct, Cache: &CacheConfig{Public: true, Private: true}}
+	if _, err := new(Router).newRoute(rt); err != ErrorInvalidCache {
+		t.Errorf("err = %v, want %v", err, ErrorInvalidCache)
+	}
+}
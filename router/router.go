@@ -4,14 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/justinas/alice"
+	"github.com/ninlil/butler/auth"
+	"github.com/ninlil/butler/clock"
 	"github.com/ninlil/butler/log"
 	"github.com/ninlil/butler/runtime"
+	"github.com/ninlil/butler/tracing"
 )
 
 // Router constants
@@ -24,6 +29,8 @@ var (
 	tResponseWriter = reflect.TypeOf(new(http.ResponseWriter)).Elem()
 	tRequest        = reflect.TypeOf(new(http.Request))
 	tContext        = reflect.TypeOf(new(context.Context)).Elem()
+	tClientInfo     = reflect.TypeOf(ClientInfo{})
+	tPrincipal      = reflect.TypeOf(auth.Principal{})
 	// tError          = reflect.TypeOf(new(error)).Elem()
 	tTime = reflect.TypeOf(time.Now())
 	tDur  = reflect.TypeOf(time.Second)
@@ -93,31 +100,91 @@ type Route struct {
 	Method  string
 	Path    string
 	Handler interface{}
+
+	// Middlewares are applied to this route only, after the router-wide middlewares
+	Middlewares []func(http.Handler) http.Handler
+
+	// CORS overrides the router-wide CORS-configuration (see WithCORS) for this route
+	CORS *CORSConfig
+
+	// RateLimit overrides the router-wide rate-limit (see WithRateLimit) for this route
+	RateLimit *RateLimitConfig
+
+	// Auth overrides the router-wide authentication (see WithAuth) for this route
+	Auth *AuthConfig
+
+	// Roles required by this route, the principal must have any of them (403 if not)
+	Roles []string
+
+	// Scopes required by this route, the principal must have all of them (403 if not)
+	Scopes []string
+
+	// Policy is a function returning an error to deny the request (403, or 401 for ErrUnauthorized),
+	// taking the same kind of arguments as a handler, and the bound arguments of the handler
+	Policy interface{}
+
+	// Cache declares how the responses may be cached ('Cache-Control' and 'Vary'), see also WithResponseCache
+	Cache *CacheConfig
+
+	// Idempotency replays the first response of requests repeated with the same 'Idempotency-Key'
+	Idempotency *IdempotencyConfig
+
+	// SecurityHeaders overrides the router-wide security-headers (see WithSecurityHeaders) for this route
+	SecurityHeaders *SecurityHeadersConfig
+
+	// Concurrency limits the requests handled at the same time by this route, in addition to the
+	// router-wide limit (see WithConcurrency)
+	Concurrency *ConcurrencyConfig
+
 	fnType  reflect.Type
 	fnValue reflect.Value
 	isRaw   bool // if Handler is a regular http.HandlerFunc, then no wrapping is needed
 
+	policyType  reflect.Type
+	policyValue reflect.Value
+
+	defaultStatus int // status used when the handler doesn't return one (0 = 200/204)
+	rateLimit     *rateLimiter
+	concurrency   *concurrencyLimiter
+	idempotency   *idempotency
+
 	router *Router
 }
 
 // Router is the handler which serves your routes
 type Router struct {
 	// options
-	name          string
-	strictSlash   bool
-	port          int
-	healthPath    string
-	readyPath     string
-	prefix        string
-	exposedErrors bool
-	skip204       bool
-	middlewares   []func(http.Handler) http.Handler
+	name            string
+	strictSlash     bool
+	port            int
+	healthPath      string
+	readyPath       string
+	prefix          string
+	exposedErrors   bool
+	skip204         bool
+	preStopDelay    time.Duration
+	cors            *CORSConfig
+	notFound        *Route
+	idOptions       []IDOption
+	accessLog       *AccessLogConfig
+	trustedProxies  []netip.Prefix
+	rateLimit       *rateLimiter
+	concurrency     *concurrencyLimiter
+	compression     *compression
+	maxDecompressed int64 // 0 is the default, -1 turns decompression off
+	auth            *AuthConfig
+	security        *SecurityHeadersConfig
+	cache           *responseCache
+	recorder        *recorder
+	middlewares     []func(http.Handler) http.Handler
 
 	// runtime
-	router *http.ServeMux
-	routes []*Route
-	server *http.Server
-	mutex  sync.Mutex
+	table       atomic.Pointer[routeTable]
+	routes      []*Route
+	routesMutex sync.Mutex
+	server      *http.Server
+	mutex       sync.Mutex
+	logged      atomic.Uint64 // successful requests seen by the access-log, for sampling
 }
 
 func (rt *Route) init() error {
@@ -171,24 +238,51 @@ func New(routes []Route, opts ...Option) (*Router, error) {
 	if router.name == "" {
 		router.name = "default"
 	}
-
-	router.router = http.NewServeMux()
+	if router.concurrency != nil {
+		router.concurrency.reason = router.overloadedReason(nil)
+	}
 
 	for i := range routes {
-		route := routes[i]
-		if err := route.init(); err != nil {
-			log.Fatal().Msg(err.Error())
+		route, err := router.newRoute(routes[i])
+		if err != nil {
+			return nil, err
 		}
-		router.routes = append(router.routes, &route)
-	}
-
-	for i := range router.routes {
-		router.routes[i].router = router
+		router.routes = append(router.routes, route)
 	}
 
 	return router, nil
 }
 
+// newRoute makes an initialized copy of the route, belonging to the router
+func (r *Router) newRoute(route Route) (*Route, error) {
+	if err := route.init(); err != nil {
+		return nil, err
+	}
+	route.router = r
+	if err := route.CORS.validate(); err != nil {
+		return nil, err
+	}
+	if err := route.Auth.validate(); err != nil {
+		return nil, err
+	}
+	if err := route.initPolicy(); err != nil {
+		return nil, err
+	}
+	if err := route.Cache.validate(); err != nil {
+		return nil, err
+	}
+	if err := route.initIdempotency(); err != nil {
+		return nil, err
+	}
+	if err := route.initRateLimit(); err != nil {
+		return nil, err
+	}
+	if err := route.initConcurrency(); err != nil {
+		return nil, err
+	}
+	return &route, nil
+}
+
 func (r *Router) goServe() {
 	if err := r.Serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Error().Msgf("router.Serve-error: %v", err)
@@ -197,63 +291,29 @@ func (r *Router) goServe() {
 
 // Serve starts the http-server on the router
 func (r *Router) Serve() error {
+	return r.serve(nil)
+}
+
+// serve starts the http-server on the listener, or on the port of the router if nil
+func (r *Router) serve(ln net.Listener) error {
+	server := &http.Server{Addr: fmt.Sprintf(":%d", r.port), Handler: r}
+	r.mutex.Lock()
 	if r.server != nil {
+		r.mutex.Unlock()
 		return ErrRouterAlreadyRunning
 	}
-
-	var haveReady bool
-	var haveHealty bool
-
-	//r.router.Use(wrapWriterMW, log.NewHandler(), IDHandler(), accessLogger)
-
-	for _, route := range r.routes {
-		// log.Trace().Msgf("router: adding %s %s", route.Method, route.Path)
-		// h := r.router.NewRoute().Name(route.Name)
-		var method = "GET"
-
-		if route.Method != "" {
-			method = route.Method
+	r.server = server
+	r.mutex.Unlock()
+	defer func() {
+		r.mutex.Lock()
+		if r.server == server {
+			r.server = nil
 		}
+		r.mutex.Unlock()
+	}()
 
-		if route.Path == "/" && r.prefix != "" {
-			route.Path = r.prefix
-		} else {
-			route.Path = r.prefix + route.Path
-		}
-		// log.Trace().Msgf("router: %s -> %s", route.Name, route.Path)
-
-		switch route.Path {
-		case r.readyPath:
-			haveReady = true
-		case r.healthPath:
-			haveHealty = true
-		}
-
-		chain := alice.New().Append(wrapWriterMW)
-
-		chain = chain.Append(log.NewHandler())
-		chain = chain.Append(IDHandler())
-		chain = chain.Append(accessLogger)
-		// chain = chain.Append(hlog.RemoteAddrHandler("ip"))
-		// chain = chain.Append(hlog.UserAgentHandler("user_agent"))
-		// chain = chain.Append(hlog.RefererHandler("referer"))
-		// chain = chain.Append(hlog.RequestIDHandler("req_id", "Request-Id"))
-
-		chain = chain.Append(r.panicHandler)
-		for _, mw := range r.middlewares {
-			chain = chain.Append(alice.Constructor(mw))
-		}
-		handler := chain.ThenFunc(route.wrapHandler())
-		r.router.Handle(buildPattern(method, route.Path), handler)
-	}
-
-	if !haveHealty && r.healthPath != "" {
-		// log.Trace().Msg("router: adding /healtyz")
-		r.router.Handle("GET "+r.healthPath, http.HandlerFunc(healthyProbe))
-	}
-	if !haveReady && r.readyPath != "" {
-		// log.Trace().Msg("router: adding /readyz")
-		r.router.Handle("GET "+r.readyPath, http.HandlerFunc(readyProbe))
+	if _, err := r.loadTable(); err != nil {
+		return err
 	}
 
 	if err := running.addRouter(r); err != nil {
@@ -263,37 +323,81 @@ func (r *Router) Serve() error {
 
 	runtime.OnClose("router_"+r.name, r.Shutdown)
 
+	ClearNotReady(NotReadyShutdown) // from an earlier Shutdown, if served again
+	forwardDeprecatedFlags()
 	log.Info().Msgf("router: listening to port %s:%d%s", "", r.port, r.prefix)
 
-	r.server = &http.Server{Addr: fmt.Sprintf(":%d", r.port), Handler: r.router}
-	return r.server.ListenAndServe()
+	if ln != nil {
+		return server.Serve(ln)
+	}
+	return server.ListenAndServe()
+}
+
+// ServeHTTP serves the request using the current set of routes, which makes the router
+// usable as a regular http.Handler
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	table, err := r.loadTable()
+	if err != nil {
+		log.Error().Msgf("router: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	table.ServeHTTP(w, req)
+}
+
+// Handler returns the router as a http.Handler, with the route-table built and the middleware-chain
+// assembled as by Serve, ex for tests without opening a port (see package routertest)
+func (r *Router) Handler() http.Handler {
+	if _, err := r.loadTable(); err != nil {
+		log.Error().Msgf("router: %v", err)
+	}
+	return r
 }
 
 // Shutdown does a graceful shutdown of the router
 func (r *Router) Shutdown() {
 	r.mutex.Lock()
-	defer r.mutex.Unlock()
+	server := r.server
+	r.server = nil
+	r.mutex.Unlock()
 
-	if r.server == nil {
+	if server == nil {
 		return
 	}
 	log.Trace().Msg("router: shutdown initiated...")
 
+	SetNotReady(NotReadyShutdown)
+	if r.preStopDelay > 0 {
+		if wait := r.preStopDelay - clock.Since(notReadySince(NotReadyShutdown)); wait > 0 {
+			log.Trace().Msgf("router: waiting %v before closing listeners", wait)
+			time.Sleep(wait)
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
 
-	err := r.server.Shutdown(ctx)
+	err := server.Shutdown(ctx)
 	if err != nil && errors.Is(err, http.ErrServerClosed) {
 		log.Error().Msgf("router: shutdown-error: %v", err)
 	}
-	r.server = nil
 	log.Trace().Msg("router: shutdown complete")
 }
 
 func (rt *Route) wrapHandler() http.HandlerFunc {
 	if rt.isRaw {
 		log.Trace().Msgf("router: %s %s is a raw handler, no wrapping needed", rt.Method, rt.Path)
-		return rt.Handler.(func(http.ResponseWriter, *http.Request))
+		handler := rt.Handler.(func(http.ResponseWriter, *http.Request))
+		if rt.Policy == nil {
+			return handler
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			if status, err := rt.checkPolicy(w, r, nil); err != nil {
+				rt.writeError(err, w, r, status)
+				return
+			}
+			handler(w, r)
+		}
 	}
 
 	log.Trace().Msgf("router: wrapping %s %s", rt.Method, rt.Path)
@@ -304,28 +408,42 @@ func (rt *Route) wrapHandler() http.HandlerFunc {
 
 func (rt *Route) writeError(err error, w http.ResponseWriter, r *http.Request, code int) {
 	if code == 0 {
-		code = http.StatusBadRequest
+		code = rt.defaultStatus
 	}
-	var result struct {
-		Error interface{} `json:"error"`
+	if code == 0 {
+		code = http.StatusBadRequest
 	}
+	var result errorResult
 	var fe *FieldError
 	if errors.As(err, &fe) {
 		result.Error = fe
 	} else {
 		result.Error = err.Error()
 	}
-	rt.writeResponse(w, r, code, result)
+	rt.writeResponse(w, r, code, &result)
 }
 
 func (rt *Route) wrap(w http.ResponseWriter, r *http.Request) {
 	log := log.FromCtx(r.Context())
 	defer r.Body.Close()
 
+	_, span := tracing.Start(r.Context(), "bind")
 	args, err := rt.createArgs(w, r)
+	span.RecordError(err)
+	span.End()
 	if err != nil {
 		log.Error().Msg(err.Error())
-		rt.writeError(err, w, r, 0)
+		var status int
+		if errors.Is(err, ErrBodyTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		rt.writeError(err, w, r, status)
+		return
+	}
+
+	if status, err := rt.checkPolicy(w, r, args); err != nil {
+		log.Debug().Msgf("router: denied by policy: %v", err)
+		rt.writeError(err, w, r, status)
 		return
 	}
 
@@ -0,0 +1,152 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Handle the matched route...
+type routeKey struct{}
+
+// RouteFromRequest returns the route matching the request, if any
+func RouteFromRequest(r *http.Request) *Route {
+	if r == nil {
+		return nil
+	}
+	return RouteFromCtx(r.Context())
+}
+
+// RouteFromCtx returns the route associated to the context, if any.
+// The route is shared between requests and must not be modified.
+func RouteFromCtx(ctx context.Context) *Route {
+	if route, ok := ctx.Value(routeKey{}).(*Route); ok {
+		return route
+	}
+	return nil
+}
+
+// contextHandler adds the route to the context of the request
+func (rt *Route) contextHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, rt)))
+	})
+}
+
+// id identifies the route in store keys and readiness reasons, by its name or else by its method and path
+func (rt *Route) id() string {
+	if rt.Name != "" {
+		return rt.Name
+	}
+	return rt.Method + " " + rt.Path
+}
+
+// Routes returns a copy of all routes of the router, with the router-prefix applied to the paths
+func (r *Router) Routes() []Route {
+	r.routesMutex.Lock()
+	defer r.routesMutex.Unlock()
+
+	routes := make([]Route, 0, len(r.routes))
+	for _, route := range r.routes {
+		rt := *route
+		rt.Path = r.fullPath(route.Path)
+		routes = append(routes, rt)
+	}
+	return routes
+}
+
+// findRoute returns the (first) route with the given name, unnamed routes are never found
+func (r *Router) findRoute(name string) *Route {
+	if name == "" {
+		return nil
+	}
+	r.routesMutex.Lock()
+	defer r.routesMutex.Unlock()
+
+	for _, route := range r.routes {
+		if route.Name == name {
+			return route
+		}
+	}
+	return nil
+}
+
+// URL builds the path for the named route, where params are pairs of names and values for the
+// {param}s in the path (ex: "id", "42"). Any params not in the path are added as query-parameters.
+func (r *Router) URL(name string, params ...string) (string, error) {
+	route := r.findRoute(name)
+	if route == nil {
+		return "", ErrRouteNotFound
+	}
+	return ExpandPath(r.fullPath(route.Path), params...)
+}
+
+// ExpandPath fills the {param}s of a route-path in the same way as URL, for paths not
+// belonging to a Router (ex: in generated clients).
+func ExpandPath(path string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", ErrURLParams
+	}
+
+	values := make(map[string]string, len(params)/2)
+	var keys []string
+	for i := 0; i < len(params); i += 2 {
+		if _, found := values[params[i]]; !found {
+			keys = append(keys, params[i])
+		}
+		values[params[i]] = params[i+1]
+	}
+
+	var sb strings.Builder
+	path = convertPath(path)
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			sb.WriteString(path)
+			break
+		}
+		end := strings.IndexByte(path[start:], '}')
+		if end < 0 {
+			return "", ErrURLParams
+		}
+		end += start
+
+		sb.WriteString(path[:start])
+		param := path[start+1 : end]
+		path = path[end+1:]
+
+		if param == "$" {
+			continue
+		}
+		param, rest := strings.CutSuffix(param, "...")
+		value, found := values[param]
+		if !found {
+			return "", fmt.Errorf("%w: '%s' is missing", ErrURLParams, param)
+		}
+		delete(values, param)
+
+		if rest {
+			segments := strings.Split(value, "/")
+			for i := range segments {
+				segments[i] = url.PathEscape(segments[i])
+			}
+			sb.WriteString(strings.Join(segments, "/"))
+		} else {
+			sb.WriteString(url.PathEscape(value))
+		}
+	}
+
+	if len(values) > 0 {
+		query := make(url.Values, len(values))
+		for _, key := range keys {
+			if value, found := values[key]; found {
+				query.Set(key, value)
+			}
+		}
+		sb.WriteByte('?')
+		sb.WriteString(query.Encode())
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+)
+
+func handlerRouteName(ctx context.Context) string {
+	if route := RouteFromCtx(ctx); route != nil {
+		return route.Name
+	}
+	return ""
+}
+
+func TestRouteFromCtx(t *testing.T) {
+	var buf bytes.Buffer
+	orgLogger := zlog.Logger
+	zlog.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { zlog.Logger = orgLogger })
+
+	h := buildTestHandler(t, []Route{
+		{Name: "whoami", Method: "GET", Path: "/whoami", Handler: handlerRouteName},
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/whoami", nil))
+
+	if body := strings.Trim(w.Body.String(), `"`+"\n"); body != "whoami" {
+		t.Errorf("route name = %q, want %q", body, "whoami")
+	}
+	if !strings.Contains(buf.String(), `"route":"whoami"`) {
+		t.Errorf("access log = %q, want it to contain the route name", buf.String())
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	r, err := New(append(
+		[]Route{{Name: "a", Method: "GET", Path: "/a", Handler: handlerReturnStatus}},
+		Group("/g", []Route{{Name: "b", Method: "GET", Path: "/b", Handler: handlerReturnStatus}})...,
+	), WithPrefix("/api"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	routes := r.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("len(Routes()) = %d, want 2", len(routes))
+	}
+	if routes[0].Path != "/api/a" || routes[1].Path != "/api/g/b" {
+		t.Errorf("paths = %q, %q, want %q, %q", routes[0].Path, routes[1].Path, "/api/a", "/api/g/b")
+	}
+}
+
+func TestURL(t *testing.T) {
+	r, err := New([]Route{
+		{Name: "item", Method: "GET", Path: "/items/{id}", Handler: handlerPathParam},
+		{Name: "files", Method: "GET", Path: "/files/*", Handler: handlerWildcardValue},
+		{Name: "rest", Method: "GET", Path: "/rest/{path...}", Handler: handlerWildcardValue},
+		{Name: "root", Method: "GET", Path: "/{$}", Handler: handlerReturnStatus},
+		{Method: "GET", Path: "/unnamed", Handler: handlerReturnStatus},
+	}, WithPrefix("/api"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		params  []string
+		want    string
+		wantErr error
+	}{
+		{"item", []string{"id", "42"}, "/api/items/42", nil},
+		{"item", []string{"id", "a b/c"}, "/api/items/a%20b%2Fc", nil},
+		{"item", []string{"id", "1", "q", "x", "a", "y"}, "/api/items/1?a=y&q=x", nil},
+		{"files", []string{"urlsuffix", "a/b c.txt"}, "/api/files/a/b%20c.txt", nil},
+		{"rest", []string{"path", "x/y"}, "/api/rest/x/y", nil},
+		{"root", nil, "/api/", nil},
+		{"item", nil, "", ErrURLParams},
+		{"item", []string{"id"}, "", ErrURLParams},
+		{"missing", nil, "", ErrRouteNotFound},
+		{"", nil, "", ErrRouteNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name+"/"+tc.want, func(t *testing.T) {
+			got, err := r.URL(tc.name, tc.params...)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("URL(%q, %q) error = %v, want %v", tc.name, tc.params, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("URL(%q, %q) = %q, want %q", tc.name, tc.params, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	got, err := ExpandPath("/items/{id}/*", "id", "7", "urlsuffix", "a/b", "q", "x")
+	if err != nil || got != "/items/7/a/b?q=x" {
+		t.Errorf("ExpandPath = %q, %v", got, err)
+	}
+	if _, err := ExpandPath("/items/{id}"); !errors.Is(err, ErrURLParams) {
+		t.Errorf("err = %v, want %v", err, ErrURLParams)
+	}
+}
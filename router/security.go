@@ -0,0 +1,183 @@
+package router
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ninlil/butler/bufferedresponse"
+	"github.com/ninlil/butler/clock"
+)
+
+const (
+	// OmitHeader as the value of a header in SecurityHeadersConfig leaves the header out
+	OmitHeader = "-"
+
+	// NoncePlaceholder in the Content-Security-Policy is replaced by the nonce of the request
+	NoncePlaceholder = "{nonce}"
+
+	defaultHSTSMaxAge     = 365 * 24 * time.Hour
+	defaultContentType    = "nosniff"
+	defaultFrameOptions   = "DENY"
+	defaultReferrerPolicy = "strict-origin-when-cross-origin"
+	defaultCSP            = "default-src 'none'; frame-ancestors 'none'"
+)
+
+// SecurityHeadersConfig is the security-headers of a router (see WithSecurityHeaders), a group of routes
+// (see SecureGroup) or a single route.
+//
+// Empty values use the defaults, and OmitHeader leaves a header out.
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge is the max-age of 'Strict-Transport-Security' (default 1 year), a negative value leaves it out
+	HSTSMaxAge time.Duration
+
+	// HSTSIncludeSubdomains adds 'includeSubDomains' to 'Strict-Transport-Security'
+	HSTSIncludeSubdomains bool
+
+	// HSTSPreload adds 'preload' to 'Strict-Transport-Security'
+	HSTSPreload bool
+
+	// ContentTypeOptions is the 'X-Content-Type-Options' (default "nosniff")
+	ContentTypeOptions string
+
+	// FrameOptions is the 'X-Frame-Options' (default "DENY")
+	FrameOptions string
+
+	// ReferrerPolicy is the 'Referrer-Policy' (default "strict-origin-when-cross-origin")
+	ReferrerPolicy string
+
+	// ContentSecurityPolicy is the 'Content-Security-Policy' (default "default-src 'none'; frame-ancestors 'none'"),
+	// where NoncePlaceholder is replaced by a new nonce for each request (see CSPNonceFromCtx),
+	// the responses of routes with a nonce aren't kept by the response cache
+	ContentSecurityPolicy string
+
+	// CacheErrors lets error-responses (4xx and 5xx) be cached, instead of 'Cache-Control: no-store'
+	CacheErrors bool
+
+	// Disabled turns the headers off, used to exclude routes from the router-wide configuration
+	Disabled bool
+}
+
+type nonceKey struct{}
+
+// WithSecurityHeaders adds security-headers to all responses, use SecureGroup or Route.SecurityHeaders
+// to override for some routes
+func WithSecurityHeaders(config SecurityHeadersConfig) Option {
+	return func(r *Router) error {
+		r.security = &config
+		return nil
+	}
+}
+
+// SecureGroup returns a copy of the routes using the security-headers, to be used like Group:
+//
+//	routes := append(apiRoutes,
+//		router.SecureGroup(pageRoutes, router.SecurityHeadersConfig{FrameOptions: "SAMEORIGIN"})...,
+//	)
+func SecureGroup(routes []Route, config SecurityHeadersConfig) []Route {
+	result := make([]Route, 0, len(routes))
+	for _, route := range routes {
+		if route.SecurityHeaders == nil {
+			route.SecurityHeaders = &config
+		}
+		result = append(result, route)
+	}
+	return result
+}
+
+// CSPNonceFromCtx returns the nonce of the 'Content-Security-Policy' of the request, if any
+func CSPNonceFromCtx(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceKey{}).(string)
+	return nonce
+}
+
+// securityConfig returns the active security-headers for the route (or the router when nil), or nil if none
+func (r *Router) securityConfig(rt *Route) *SecurityHeadersConfig {
+	cfg := r.security
+	if rt != nil && rt.SecurityHeaders != nil {
+		cfg = rt.SecurityHeaders
+	}
+	if cfg == nil || cfg.Disabled {
+		return nil
+	}
+	return cfg
+}
+
+// usesNonce is true when the Content-Security-Policy has a new nonce for each request
+func (cfg *SecurityHeadersConfig) usesNonce() bool {
+	return cfg != nil && strings.Contains(cfg.ContentSecurityPolicy, NoncePlaceholder)
+}
+
+// securityHandler is the middleware adding the security-headers to the response
+func (r *Router) securityHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cfg := r.securityConfig(RouteFromCtx(req.Context()))
+		if cfg == nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		h := w.Header()
+		setHeader(h, "Strict-Transport-Security", cfg.hsts())
+		setHeader(h, "X-Content-Type-Options", orDefault(cfg.ContentTypeOptions, defaultContentType))
+		setHeader(h, "X-Frame-Options", orDefault(cfg.FrameOptions, defaultFrameOptions))
+		setHeader(h, "Referrer-Policy", orDefault(cfg.ReferrerPolicy, defaultReferrerPolicy))
+
+		csp := orDefault(cfg.ContentSecurityPolicy, defaultCSP)
+		if strings.Contains(csp, NoncePlaceholder) {
+			nonce := newNonce()
+			csp = strings.ReplaceAll(csp, NoncePlaceholder, nonce)
+			req = req.WithContext(context.WithValue(req.Context(), nonceKey{}, nonce))
+		}
+		setHeader(h, "Content-Security-Policy", csp)
+
+		next.ServeHTTP(w, req)
+
+		if w2, ok := bufferedresponse.Get(w); ok && w2.Status() >= 400 && !cfg.CacheErrors {
+			h.Set("Cache-Control", "no-store")
+		}
+	})
+}
+
+// hsts returns the value of the 'Strict-Transport-Security' header
+func (cfg *SecurityHeadersConfig) hsts() string {
+	maxAge := cfg.HSTSMaxAge
+	if maxAge == 0 {
+		maxAge = defaultHSTSMaxAge
+	}
+	if maxAge < 0 {
+		return ""
+	}
+	value := "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	if cfg.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if cfg.HSTSPreload {
+		value += "; preload"
+	}
+	return value
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// setHeader sets the header, unless the value is empty or OmitHeader
+func setHeader(h http.Header, key, value string) {
+	if value != "" && value != OmitHeader {
+		h.Set(key, value)
+	}
+}
+
+// newNonce returns 128 random bits, base64-encoded
+func newNonce() string {
+	var buf [16]byte
+	clock.Read(buf[:])
+	return base64.StdEncoding.EncodeToString(buf[:])
+}
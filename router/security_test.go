@@ -0,0 +1,116 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func handlerNonce(ctx context.Context) string {
+	return CSPNonceFromCtx(ctx)
+}
+
+func handlerPanic() string {
+	panic("boom")
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	pages := SecureGroup([]Route{
+		{Name: "page", Method: "GET", Path: "/page", Handler: handlerNonce},
+	}, SecurityHeadersConfig{FrameOptions: "SAMEORIGIN", ContentSecurityPolicy: "script-src 'nonce-{nonce}'", HSTSMaxAge: -1})
+	routes := append([]Route{
+		{Name: "api", Method: "GET", Path: "/api", Handler: handlerReturnStruct},
+		{Name: "error", Method: "GET", Path: "/error", Handler: handlerReturnError},
+		{Name: "panic", Method: "GET", Path: "/panic", Handler: handlerPanic},
+		{Name: "plain", Method: "GET", Path: "/plain", Handler: handlerReturnStruct, SecurityHeaders: &SecurityHeadersConfig{Disabled: true}},
+	}, pages...)
+	h := buildTestHandlerWithOpts(t, routes, WithSecurityHeaders(SecurityHeadersConfig{
+		HSTSIncludeSubdomains: true,
+		ReferrerPolicy:        OmitHeader,
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", "text/plain")
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		w := serve("/api")
+		want := map[string]string{
+			"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+			"X-Content-Type-Options":    "nosniff",
+			"X-Frame-Options":           "DENY",
+			"Referrer-Policy":           "",
+			"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+			"Cache-Control":             "",
+		}
+		for key, value := range want {
+			if got := w.Header().Get(key); got != value {
+				t.Errorf("%s = %q, want %q", key, got, value)
+			}
+		}
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		for _, path := range []string{"/error", "/panic", "/missing"} {
+			w := serve(path)
+			if w.Code < 400 || w.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("%s: got %d with Cache-Control %q", path, w.Code, w.Header().Get("Cache-Control"))
+			}
+		}
+	})
+
+	t.Run("group", func(t *testing.T) {
+		w := serve("/page")
+		nonce := w.Body.String()
+		if nonce == "" || w.Header().Get("Content-Security-Policy") != "script-src 'nonce-"+nonce+"'" {
+			t.Errorf("nonce %q, csp %q", nonce, w.Header().Get("Content-Security-Policy"))
+		}
+		if w.Header().Get("X-Frame-Options") != "SAMEORIGIN" || w.Header().Get("Strict-Transport-Security") != "" {
+			t.Errorf("headers = %v", w.Header())
+		}
+		if next := serve("/page").Body.String(); next == nonce {
+			t.Error("nonce is reused")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		w := serve("/plain")
+		for key := range w.Header() {
+			if strings.HasPrefix(key, "X-Frame") || key == "Content-Security-Policy" {
+				t.Errorf("unexpected header %s", key)
+			}
+		}
+	})
+}
+
+func TestWithoutSecurityHeaders(t *testing.T) {
+	h := buildTestHandler(t, []Route{{Name: "api", Method: "GET", Path: "/api", Handler: handlerReturnStruct}})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/api", nil))
+	if w.Header().Get("X-Content-Type-Options") != "" || w.Code != http.StatusOK {
+		t.Errorf("got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestSecurityNonceNotCached(t *testing.T) {
+	routes := []Route{{Name: "page", Method: "GET", Path: "/page", Handler: handlerNonce, Cache: &CacheConfig{MaxAge: time.Minute}}}
+	h := buildTestHandlerWithOpts(t, routes, WithResponseCache(10, 0),
+		WithSecurityHeaders(SecurityHeadersConfig{ContentSecurityPolicy: "script-src 'nonce-{nonce}'"}))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/page", nil)
+		req.Header.Set("Accept", "text/plain")
+		h.ServeHTTP(w, req)
+		if csp := w.Header().Get("Content-Security-Policy"); csp != "script-src 'nonce-"+w.Body.String()+"'" || w.Header().Get("Age") != "" {
+			t.Errorf("request %d: body %q, CSP %q, Age %q", i+1, w.Body.String(), csp, w.Header().Get("Age"))
+		}
+	}
+}
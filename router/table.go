@@ -0,0 +1,228 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/justinas/alice"
+	"github.com/ninlil/butler/log"
+)
+
+// routeTable is the compiled set of routes serving the requests of a Router
+type routeTable struct {
+	mux       *http.ServeMux
+	patterns  map[string]*Route // registered pattern -> route
+	methods   []string          // all methods used by any pattern
+	fallback  *Route            // used for writing errors on unmatched requests
+	notFound  http.HandlerFunc
+	unmatched http.Handler
+	cors      *CORSConfig
+	limiters  []*concurrencyLimiter // reported by the readiness-probe
+}
+
+// fullPath returns the path of a route with the router-prefix applied
+func (r *Router) fullPath(path string) string {
+	return joinPath(r.prefix, path)
+}
+
+// buildTable creates a new routeTable with all routes and probes registered
+func (r *Router) buildTable() (table *routeTable, err error) {
+	var haveReady bool
+	var haveHealty bool
+
+	table = &routeTable{
+		mux:      http.NewServeMux(),
+		patterns: make(map[string]*Route),
+		fallback: &Route{router: r},
+		notFound: r.notFoundRoute().wrapHandler(),
+		cors:     r.cors,
+	}
+	if r.concurrency != nil {
+		table.limiters = append(table.limiters, r.concurrency)
+	}
+	methods := map[string]bool{http.MethodGet: true}
+
+	// ServeMux panics on invalid or conflicting patterns
+	defer func() {
+		if p := recover(); p != nil {
+			table = nil
+			err = fmt.Errorf("router: %v", p)
+		}
+	}()
+
+	for _, route := range r.routes {
+		var method = "GET"
+
+		if route.Method != "" {
+			method = strings.ToUpper(route.Method)
+		}
+		if method != All {
+			methods[method] = true
+		}
+
+		path := r.fullPath(route.Path)
+		// log.Trace().Msgf("router: %s -> %s", route.Name, path)
+
+		switch path {
+		case r.readyPath:
+			haveReady = true
+		case r.healthPath:
+			haveHealty = true
+		}
+
+		pattern := buildPattern(method, path)
+		table.mux.Handle(pattern, r.routeHandler(route))
+		table.patterns[pattern] = route
+		if route.concurrency != nil {
+			table.limiters = append(table.limiters, route.concurrency)
+		}
+	}
+
+	if !haveHealty && r.healthPath != "" {
+		// log.Trace().Msg("router: adding /healtyz")
+		table.mux.Handle("GET "+r.healthPath, http.HandlerFunc(healthyProbe))
+	}
+	if !haveReady && r.readyPath != "" {
+		// log.Trace().Msg("router: adding /readyz")
+		table.mux.Handle("GET "+r.readyPath, http.HandlerFunc(table.readyProbe))
+	}
+
+	for method := range methods {
+		table.methods = append(table.methods, method)
+	}
+	sort.Strings(table.methods)
+
+	table.unmatched = r.baseChain().ThenFunc(table.serveUnmatched)
+
+	return table, nil
+}
+
+// baseChain is the built-in part of the middleware-chain, used by all routes
+func (r *Router) baseChain() alice.Chain {
+	chain := alice.New().Append(wrapWriterMW)
+	if r.compression != nil {
+		chain = chain.Append(r.compression.handler)
+	}
+	if r.recorder != nil {
+		chain = chain.Append(r.recorder.handler)
+	}
+
+	chain = chain.Append(log.NewHandler())
+	chain = chain.Append(r.clientHandler)
+	chain = chain.Append(IDHandler(r.idOptions...))
+	chain = chain.Append(tracingHandler)
+	chain = chain.Append(r.accessLogger)
+	chain = chain.Append(r.securityHandler)
+
+	return chain.Append(r.panicHandler)
+}
+
+// routeHandler builds the middleware-chain for a single route.
+//
+// The order is: the built-in chain, router-middlewares, route-middlewares (including
+// those added by Group) and finally the handler itself.
+func (r *Router) routeHandler(route *Route) http.Handler {
+	chain := alice.New(route.contextHandler).Extend(r.baseChain())
+
+	if cors := route.corsConfig(); cors != nil {
+		chain = chain.Append(cors.handler)
+	}
+	if limiter := route.rateLimiter(); limiter != nil {
+		chain = chain.Append(limiter.handler(route))
+	}
+	for _, limiter := range route.concurrencyLimiters() {
+		chain = chain.Append(limiter.handler(route))
+	}
+	if cfg := route.authConfig(); cfg != nil {
+		chain = chain.Append(cfg.handler(route))
+	}
+	if len(route.Roles) > 0 || len(route.Scopes) > 0 {
+		chain = chain.Append(route.authorize)
+	}
+	if r.cache != nil && route.Cache.ttl() > 0 && !r.securityConfig(route).usesNonce() {
+		chain = chain.Append(r.cache.handler(route))
+	}
+	if r.maxDecompressed >= 0 {
+		chain = chain.Append(r.decompressHandler(route))
+	}
+	if route.idempotency != nil {
+		chain = chain.Append(route.idempotency.handler(route))
+	}
+	for _, mw := range r.middlewares {
+		chain = chain.Append(alice.Constructor(mw))
+	}
+	for _, mw := range route.Middlewares {
+		chain = chain.Append(alice.Constructor(mw))
+	}
+	return chain.ThenFunc(route.wrapHandler())
+}
+
+// ServeHTTP dispatches the request to the matching route, or to the handler for unmatched requests
+func (table *routeTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := table.mux.Handler(r); pattern == "" {
+		table.unmatched.ServeHTTP(w, r)
+		return
+	}
+	table.mux.ServeHTTP(w, r)
+}
+
+// lookup returns the route (if any) that would handle the request using another method
+func (table *routeTable) lookup(r *http.Request, method string) (*Route, bool) {
+	r2 := *r
+	r2.Method = method
+	_, pattern := table.mux.Handler(&r2)
+	if pattern == "" {
+		return nil, false
+	}
+	return table.patterns[pattern], true
+}
+
+// allowedMethods returns the methods that have a route matching the path of the request
+func (table *routeTable) allowedMethods(r *http.Request) []string {
+	var allowed []string
+	for _, method := range table.methods {
+		if _, found := table.lookup(r, method); found {
+			allowed = append(allowed, method)
+			if method == http.MethodGet {
+				allowed = append(allowed, http.MethodHead)
+			}
+		}
+	}
+	if len(allowed) > 0 {
+		allowed = append(allowed, http.MethodOptions)
+		sort.Strings(allowed)
+	}
+	return allowed
+}
+
+// serveUnmatched handles requests not matching any route with 404, 405 or an automatic OPTIONS-response
+func (table *routeTable) serveUnmatched(w http.ResponseWriter, r *http.Request) {
+	allowed := table.allowedMethods(r)
+
+	if len(allowed) == 0 {
+		table.cors.writeHeaders(w, r)
+		table.notFound(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+
+	if r.Method != http.MethodOptions {
+		table.cors.writeHeaders(w, r)
+		table.fallback.writeError(ErrMethodNotAllowed, w, r, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if isPreflight(r) {
+		cors := table.cors
+		if route, found := table.lookup(r, r.Header.Get(hdrRequestMethod)); found {
+			if route != nil {
+				cors = route.corsConfig()
+			}
+			cors.writePreflight(w, r, allowed)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
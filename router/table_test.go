@@ -0,0 +1,46 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotFound(t *testing.T) {
+	h := buildTestHandler(t, []Route{
+		{Name: "item", Method: "GET", Path: "/item", Handler: handlerReturnStruct},
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w.Header().Get("X-Request-Id") == "" {
+		t.Error("expected X-Request-Id on 404 response")
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not valid JSON: %v", err)
+	}
+	if body.Error != ErrNotFound.Error() {
+		t.Errorf("error = %q, want %q", body.Error, ErrNotFound.Error())
+	}
+}
+
+func TestAutomaticOptions(t *testing.T) {
+	h := buildTestHandler(t, []Route{
+		{Name: "get", Method: "GET", Path: "/items", Handler: handlerReturnStruct},
+		{Name: "post", Method: "POST", Path: "/items", Handler: handlerReturnStatus},
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/items", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("Allow = %q, want %q", allow, "GET, HEAD, OPTIONS, POST")
+	}
+}
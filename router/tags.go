@@ -6,6 +6,8 @@ import (
 	"reflect"
 	"strconv"
 	"time"
+
+	"github.com/ninlil/butler/clock"
 )
 
 type fromSource int
@@ -272,7 +274,11 @@ func (tag *tagInfo) time(f reflect.Value, txt string, force bool) error {
 	return nil
 }
 
+// parseTime parses the date/time in one of the supported formats, or "now" as the current time (of the clock)
 func parseTime(txt string) (time.Time, error) {
+	if txt == "now" {
+		return clock.Now(), nil
+	}
 	if dt, err := time.Parse(time.RFC3339Nano, txt); err == nil {
 		return dt, nil
 	}
@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/ninlil/butler/clock/clocktest"
 )
 
 // --- parseTag tests ---
@@ -184,6 +186,28 @@ func TestTagInfo_Duration(t *testing.T) {
 	}
 }
 
+// --- tagInfo.time tests ---
+
+func TestTagInfo_TimeNow(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake := clocktest.UseFake(t, start)
+
+	var v time.Time
+	f := reflect.ValueOf(&v).Elem()
+	if err := (&tagInfo{}).time(f, "now", false); err != nil || !v.Equal(start) {
+		t.Errorf("now = %v (%v), want %v", v, err, start)
+	}
+
+	tag := tagInfo{HasMax: true, Max: "now"}
+	if err := tag.time(f, "2024-01-02", false); err != nil {
+		t.Errorf("before now: %v", err)
+	}
+	fake.Set(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err := tag.time(f, "2024-01-02", false); err == nil {
+		t.Error("expected an error for a time after now")
+	}
+}
+
 // --- tagInfo.bool tests ---
 
 func newBoolField() reflect.Value {
@@ -0,0 +1,141 @@
+package router
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ninlil/butler/tracing"
+)
+
+// W3C Trace Context, see https://www.w3.org/TR/trace-context/
+
+const (
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+
+	traceStateMaxLen = 512
+)
+
+// Trace-flags
+const (
+	TraceFlagSampled byte = 0x01
+)
+
+// ErrInvalidTraceParent is returned when a 'traceparent' header can't be parsed
+var ErrInvalidTraceParent = fmt.Errorf("invalid traceparent")
+
+// TraceContext is the W3C trace-context of a request
+type TraceContext struct {
+	TraceID  string // 32 lowercase hex characters
+	SpanID   string // 16 lowercase hex characters, the span of this request
+	ParentID string // 16 lowercase hex characters, the span of the caller (if any)
+	Flags    byte
+	State    string // the 'tracestate' header, passed on as-is
+}
+
+// Sampled returns true if the sampled-flag is set
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&TraceFlagSampled != 0
+}
+
+// TraceParent returns the 'traceparent' header-value with the span of this request as parent
+func (tc TraceContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// ParseTraceParent parses a 'traceparent' header-value, returning the trace-id, parent-id and flags
+// in a TraceContext without a SpanID.
+func ParseTraceParent(value string) (TraceContext, error) {
+	var tc TraceContext
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return tc, ErrInvalidTraceParent
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// future versions may add fields, but version 00 has exactly 4
+	if !isHex(version, 1) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return tc, ErrInvalidTraceParent
+	}
+	if !isHex(traceID, 16) || isZeroHex(traceID) {
+		return tc, ErrInvalidTraceParent
+	}
+	if !isHex(parentID, 8) || isZeroHex(parentID) {
+		return tc, ErrInvalidTraceParent
+	}
+	if !isHex(flags, 1) {
+		return tc, ErrInvalidTraceParent
+	}
+	f, _ := hex.DecodeString(flags)
+
+	tc.TraceID = traceID
+	tc.ParentID = parentID
+	tc.Flags = f[0]
+	return tc, nil
+}
+
+// isHex checks for a lowercase hex-string of n bytes
+func isHex(s string, n int) bool {
+	if len(s) != n*2 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// traceFromRequest continues the trace of the caller (if any) with a new span for this request
+func traceFromRequest(r *http.Request) TraceContext {
+	tc, err := ParseTraceParent(r.Header.Get(traceParentHeader))
+	if err != nil {
+		tc = TraceContext{
+			TraceID: tracing.NewTraceID(),
+			Flags:   TraceFlagSampled,
+		}
+	} else if state := strings.Join(r.Header.Values(traceStateHeader), ","); len(state) <= traceStateMaxLen {
+		tc.State = state
+	}
+	tc.SpanID = tracing.NewSpanID()
+	return tc
+}
+
+// Handle the trace-context...
+type traceKey struct{}
+
+// TraceFromCtx returns the trace-context associated to the context if any.
+func TraceFromCtx(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey{}).(TraceContext)
+	return tc, ok
+}
+
+// TraceIDFromCtx returns the trace-id associated to the context if any.
+func TraceIDFromCtx(ctx context.Context) (id string) {
+	if tc, ok := TraceFromCtx(ctx); ok {
+		return tc.TraceID
+	}
+	return
+}
+
+// SpanIDFromCtx returns the span-id associated to the context if any.
+func SpanIDFromCtx(ctx context.Context) (id string) {
+	if tc, ok := TraceFromCtx(ctx); ok {
+		return tc.SpanID
+	}
+	return
+}
+
+// CtxWithTrace adds the given trace-context to the context
+func CtxWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, tc)
+}
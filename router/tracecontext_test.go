@@ -0,0 +1,104 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"valid", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-xyz", false},
+		{"version 00 with extra field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-xyz", true},
+		{"version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"zero trace-id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", true},
+		{"zero parent-id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true},
+		{"short trace-id", "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", true},
+		{"empty", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseTraceParent(tc.value)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidTraceParent) {
+					t.Errorf("ParseTraceParent(%q) error = %v, want ErrInvalidTraceParent", tc.value, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTraceParent(%q) error = %v", tc.value, err)
+			}
+			if got.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || got.ParentID != "00f067aa0ba902b7" || !got.Sampled() {
+				t.Errorf("ParseTraceParent(%q) = %+v", tc.value, got)
+			}
+		})
+	}
+}
+
+func handlerTrace(ctx context.Context) string {
+	return TraceIDFromCtx(ctx) + "/" + SpanIDFromCtx(ctx)
+}
+
+func TestIDHandlerTraceContext(t *testing.T) {
+	var buf bytes.Buffer
+	orgLogger := zlog.Logger
+	zlog.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { zlog.Logger = orgLogger })
+
+	h := buildTestHandler(t, []Route{
+		{Name: "trace", Method: "GET", Path: "/trace", Handler: handlerTrace},
+	})
+
+	t.Run("continues incoming trace", func(t *testing.T) {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/trace", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		req.Header.Set("tracestate", "vendor=abc")
+		h.ServeHTTP(w, req)
+
+		traceID, spanID, _ := strings.Cut(strings.Trim(w.Body.String(), `"`+"\n"), "/")
+		if traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("trace-id = %q, want the incoming trace-id", traceID)
+		}
+		if len(spanID) != 16 || spanID == "00f067aa0ba902b7" {
+			t.Errorf("span-id = %q, want a new span-id", spanID)
+		}
+		if got := w.Header().Get("traceparent"); got != "00-"+traceID+"-"+spanID+"-01" {
+			t.Errorf("traceparent = %q", got)
+		}
+		if got := w.Header().Get("tracestate"); got != "vendor=abc" {
+			t.Errorf("tracestate = %q, want %q", got, "vendor=abc")
+		}
+		if !strings.Contains(buf.String(), `"trace_id":"`+traceID+`"`) || !strings.Contains(buf.String(), `"span_id":"`+spanID+`"`) {
+			t.Errorf("log = %q, want trace_id and span_id", buf.String())
+		}
+	})
+
+	t.Run("starts a new trace", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/trace", nil)
+		req.Header.Set("traceparent", "garbage")
+		h.ServeHTTP(w, req)
+
+		parsed, err := ParseTraceParent(w.Header().Get("traceparent"))
+		if err != nil {
+			t.Fatalf("response traceparent: %v", err)
+		}
+		if got := strings.Trim(w.Body.String(), `"`+"\n"); got != parsed.TraceID+"/"+parsed.ParentID {
+			t.Errorf("ids = %q, want them to match the traceparent header", got)
+		}
+	})
+}
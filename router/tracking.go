@@ -4,7 +4,6 @@ import (
 	"context"
 	"net/http"
 
-	"github.com/rs/xid"
 	"github.com/rs/zerolog"
 )
 
@@ -19,15 +18,16 @@ const (
 // Handle the "Correlation-Id" header...
 type corrIDKey struct{}
 
-// CorrIDFromRequest returns the unique id associated to the request if any.
+// CorrIDFromRequest returns the unique id associated to the request if any, as set by IDHandler.
+// Outside of IDHandler, a valid 'X-Correlation-Id' header is used.
 func CorrIDFromRequest(r *http.Request) (id string) {
 	if r == nil {
 		return
 	}
-	if cid := r.Header.Get(correlationID); cid != "" {
+	if cid := CorrIDFromCtx(r.Context()); cid != "" {
 		return cid
 	}
-	return CorrIDFromCtx(r.Context())
+	return defaultIDConfig.incoming(r, correlationID)
 }
 
 // CorrIDFromCtx returns the unique id associated to the context if any.
@@ -46,15 +46,16 @@ func CtxWithCorrID(ctx context.Context, id string) context.Context {
 // Handle the "Request-Id" header...
 type reqIDKey struct{}
 
-// ReqIDFromRequest returns the unique id associated to the request if any.
+// ReqIDFromRequest returns the unique id associated to the request if any, as set by IDHandler.
+// Outside of IDHandler, a valid 'X-Request-Id' header is used.
 func ReqIDFromRequest(r *http.Request) (id string) {
 	if r == nil {
 		return
 	}
-	if rid := r.Header.Get(requestID); rid != "" {
+	if rid := ReqIDFromCtx(r.Context()); rid != "" {
 		return rid
 	}
-	return ReqIDFromCtx(r.Context())
+	return defaultIDConfig.incoming(r, requestID)
 }
 
 // ReqIDFromCtx returns the unique id associated to the context if any.
@@ -71,41 +72,63 @@ func CtxWithReqID(ctx context.Context, id string) context.Context {
 }
 
 // IDHandler returns a handler setting a unique id to the request which can
-// be gathered using IDFromRequest(req). This generated id is added as a field to the
-// logger using the passed fieldKey as field name. The id is also added as a response
-// header if the headerName is not empty.
+// be gathered using ReqIDFromCtx(ctx), and a correlation-id using CorrIDFromCtx(ctx).
+// The ids are added as fields to the logger and as response headers, see the IDOptions
+// for names, generator and validation of incoming ids.
 //
-// The generated id is a URL safe base64 encoded mongo object-id-like unique id.
+// The W3C trace-context ('traceparent' and 'tracestate') of the caller is continued with
+// a new span, available using TraceFromCtx(ctx).
+//
+// The default generated id is a URL safe base64 encoded mongo object-id-like unique id.
 // Mongo unique id generation algorithm has been selected as a trade-off between
 // size and ease of use: UUID is less space efficient and snowflake requires machine
 // configuration.
-func IDHandler( /*fieldKey, headerName string*/ ) func(next http.Handler) http.Handler {
+func IDHandler(opts ...IDOption) func(next http.Handler) http.Handler {
+	cfg := newIDConfig(opts...)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
 			log := zerolog.Ctx(ctx)
 
 			// Request-Id
-			rid := ReqIDFromRequest(r)
+			rid := cfg.incoming(r, cfg.requestHeader)
+			if rid == "" {
+				rid = ReqIDFromCtx(ctx)
+			}
 			if rid == "" {
-				rid = xid.New().String()
+				rid = cfg.generator()
 			}
 			ctx = CtxWithReqID(ctx, rid)
-			w.Header().Set(requestID, rid)
 
 			// Correlation-Id
-			cid := CorrIDFromRequest(r)
+			cid := cfg.incoming(r, cfg.correlationHeader)
 			if cid == "" {
-				cid = xid.New().String()
+				cid = CorrIDFromCtx(ctx)
+			}
+			if cid == "" {
+				cid = cfg.generator()
 			}
 			ctx = CtxWithCorrID(ctx, cid)
-			w.Header().Set(correlationID, cid)
+
+			// Trace-context
+			tc := traceFromRequest(r)
+			ctx = CtxWithTrace(ctx, tc)
+
+			if cfg.echo {
+				w.Header().Set(cfg.requestHeader, rid)
+				w.Header().Set(cfg.correlationHeader, cid)
+				w.Header().Set(traceParentHeader, tc.TraceParent())
+				if tc.State != "" {
+					w.Header().Set(traceStateHeader, tc.State)
+				}
+			}
 
 			r = r.WithContext(ctx)
 
 			log.UpdateContext(func(c zerolog.Context) zerolog.Context {
-				// return c.Str(fieldKey, id)
-				return c.Str("req_id", rid).Str("corr_id", cid)
+				return c.Str(cfg.requestField, rid).Str(cfg.correlationField, cid).
+					Str("trace_id", tc.TraceID).Str("span_id", tc.SpanID)
 			})
 
 			next.ServeHTTP(w, r)
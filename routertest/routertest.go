@@ -0,0 +1,368 @@
+// Package routertest tests butler-routers in-process, without opening a port:
+//
+//	func TestGetItem(t *testing.T) {
+//		rt := routertest.New(t, routes)
+//
+//		var item Item
+//		rt.GET("/items/{id}", 42).WithQuery("expand", "true").Expect(200).JSON(&item)
+//
+//		rt.GET("/items/{id}", "abc").Expect(400).ExpectFieldError("id")
+//	}
+//
+// The log of the router is captured (see Tester.Logs and Response.Logs), so tests using the same
+// package-wide logger must not run in parallel.
+package routertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+
+	"github.com/ninlil/butler/router"
+)
+
+// Tester sends requests to a router
+type Tester struct {
+	t       testing.TB
+	handler http.Handler
+	router  *router.Router
+	logs    *syncBuffer
+
+	// Header is sent with all requests
+	Header http.Header
+
+	// IDHeader is the response-header with the request-id (default "X-Request-Id")
+	IDHeader string
+}
+
+// New creates a router with the routes and options, with its log captured
+func New(t testing.TB, routes []router.Route, opts ...router.Option) *Tester {
+	t.Helper()
+	tt := newTester(t)
+	r, err := router.New(routes, opts...)
+	if err != nil {
+		t.Fatalf("routertest: %v", err)
+	}
+	tt.router = r
+	tt.handler = r.Handler() // the chain is built with the captured logger
+	return tt
+}
+
+// Wrap tests any handler, ex a router created elsewhere (only logs written after Wrap are captured)
+func Wrap(t testing.TB, h http.Handler) *Tester {
+	t.Helper()
+	tt := newTester(t)
+	tt.handler = h
+	tt.router, _ = h.(*router.Router)
+	return tt
+}
+
+func newTester(t testing.TB) *Tester {
+	tt := &Tester{t: t, logs: new(syncBuffer), Header: make(http.Header), IDHeader: "X-Request-Id"}
+	org := zlog.Logger
+	zlog.Logger = zerolog.New(tt.logs).Level(zerolog.DebugLevel)
+	t.Cleanup(func() { zlog.Logger = org })
+	return tt
+}
+
+// Router returns the router under test, nil if Wrap was used with another handler
+func (tt *Tester) Router() *router.Router {
+	return tt.router
+}
+
+// GET starts a GET-request, see Do
+func (tt *Tester) GET(path string, params ...interface{}) *Request {
+	return tt.Do(http.MethodGet, path, params...)
+}
+
+// HEAD starts a HEAD-request, see Do
+func (tt *Tester) HEAD(path string, params ...interface{}) *Request {
+	return tt.Do(http.MethodHead, path, params...)
+}
+
+// POST starts a POST-request, see Do
+func (tt *Tester) POST(path string, params ...interface{}) *Request {
+	return tt.Do(http.MethodPost, path, params...)
+}
+
+// PUT starts a PUT-request, see Do
+func (tt *Tester) PUT(path string, params ...interface{}) *Request {
+	return tt.Do(http.MethodPut, path, params...)
+}
+
+// PATCH starts a PATCH-request, see Do
+func (tt *Tester) PATCH(path string, params ...interface{}) *Request {
+	return tt.Do(http.MethodPatch, path, params...)
+}
+
+// DELETE starts a DELETE-request, see Do
+func (tt *Tester) DELETE(path string, params ...interface{}) *Request {
+	return tt.Do(http.MethodDelete, path, params...)
+}
+
+// Do starts a request, where the wildcards of the path ("{name}") are replaced by the params in order
+func (tt *Tester) Do(method, path string, params ...interface{}) *Request {
+	for _, param := range params {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start < 0 || end < start {
+			tt.t.Fatalf("routertest: more params than wildcards in %q", path)
+		}
+		path = path[:start] + url.PathEscape(fmt.Sprint(param)) + path[end+1:]
+	}
+	return &Request{tt: tt, method: method, path: path, query: make(url.Values), header: tt.Header.Clone()}
+}
+
+// Logs returns all captured log-entries
+func (tt *Tester) Logs() []LogEntry {
+	tt.t.Helper()
+	var entries []LogEntry
+	for _, line := range strings.Split(tt.logs.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry LogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			tt.t.Fatalf("routertest: invalid log-line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// LogEntry is a captured log-entry, with the fields as decoded from JSON
+type LogEntry map[string]interface{}
+
+// Level of the entry
+func (e LogEntry) Level() string {
+	s, _ := e["level"].(string)
+	return s
+}
+
+// Message of the entry
+func (e LogEntry) Message() string {
+	s, _ := e["message"].(string)
+	return s
+}
+
+// RequestID of the entry, if it was logged for a request
+func (e LogEntry) RequestID() string {
+	s, _ := e["req_id"].(string)
+	return s
+}
+
+// Request is a request being built
+type Request struct {
+	tt     *Tester
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   io.Reader
+}
+
+// WithQuery adds a query-parameter
+func (req *Request) WithQuery(key string, value interface{}) *Request {
+	req.query.Add(key, fmt.Sprint(value))
+	return req
+}
+
+// WithHeader sets a header
+func (req *Request) WithHeader(key, value string) *Request {
+	req.header.Set(key, value)
+	return req
+}
+
+// WithBody sets the body, where a string, []byte or io.Reader is sent as is and anything else as JSON
+func (req *Request) WithBody(body interface{}) *Request {
+	switch v := body.(type) {
+	case string:
+		req.body = strings.NewReader(v)
+	case []byte:
+		req.body = bytes.NewReader(v)
+	case io.Reader:
+		req.body = v
+	default:
+		buf, err := json.Marshal(v)
+		if err != nil {
+			req.tt.t.Fatalf("routertest: %v", err)
+		}
+		req.body = bytes.NewReader(buf)
+		if req.header.Get("Content-Type") == "" {
+			req.header.Set("Content-Type", "application/json")
+		}
+	}
+	return req
+}
+
+// Send sends the request
+func (req *Request) Send() *Response {
+	target := req.path
+	if len(req.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + req.query.Encode()
+	}
+	r := httptest.NewRequest(req.method, target, req.body)
+	for key, values := range req.header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	req.tt.handler.ServeHTTP(w, r)
+	return &Response{tt: req.tt, Request: r, Recorder: w}
+}
+
+// Expect sends the request, and checks the status
+func (req *Request) Expect(status int) *Response {
+	req.tt.t.Helper()
+	return req.Send().Expect(status)
+}
+
+// Response is the response of a request
+type Response struct {
+	tt       *Tester
+	Request  *http.Request
+	Recorder *httptest.ResponseRecorder
+}
+
+// Status of the response
+func (resp *Response) Status() int {
+	return resp.Recorder.Code
+}
+
+// Header of the response
+func (resp *Response) Header() http.Header {
+	return resp.Recorder.Header()
+}
+
+// Body of the response
+func (resp *Response) Body() []byte {
+	return resp.Recorder.Body.Bytes()
+}
+
+// Text returns the body as a string
+func (resp *Response) Text() string {
+	return resp.Recorder.Body.String()
+}
+
+// RequestID returns the request-id of the response
+func (resp *Response) RequestID() string {
+	return resp.Header().Get(resp.tt.IDHeader)
+}
+
+// Expect checks the status
+func (resp *Response) Expect(status int) *Response {
+	resp.tt.t.Helper()
+	if resp.Status() != status {
+		resp.tt.t.Errorf("%s %s: status %d, want %d (body %q)", resp.Request.Method, resp.Request.URL, resp.Status(), status, resp.Text())
+	}
+	return resp
+}
+
+// ExpectHeader checks the value of a header
+func (resp *Response) ExpectHeader(key, value string) *Response {
+	resp.tt.t.Helper()
+	if got := resp.Header().Get(key); got != value {
+		resp.tt.t.Errorf("%s %s: header %s = %q, want %q", resp.Request.Method, resp.Request.URL, key, got, value)
+	}
+	return resp
+}
+
+// JSON decodes the body into v
+func (resp *Response) JSON(v interface{}) *Response {
+	resp.tt.t.Helper()
+	if err := json.Unmarshal(resp.Body(), v); err != nil {
+		resp.tt.t.Fatalf("%s %s: invalid JSON %q: %v", resp.Request.Method, resp.Request.URL, resp.Text(), err)
+	}
+	return resp
+}
+
+// ExpectRequestID checks that the response has a request-id (equal to the id, if given),
+// and that the log-entries of the request carry it
+func (resp *Response) ExpectRequestID(id ...string) *Response {
+	resp.tt.t.Helper()
+	got := resp.RequestID()
+	switch {
+	case got == "":
+		resp.tt.t.Errorf("%s %s: no request-id in %s", resp.Request.Method, resp.Request.URL, resp.tt.IDHeader)
+	case len(id) > 0 && got != id[0]:
+		resp.tt.t.Errorf("%s %s: request-id %q, want %q", resp.Request.Method, resp.Request.URL, got, id[0])
+	case len(resp.Logs()) == 0:
+		resp.tt.t.Errorf("%s %s: no log-entries with the request-id %q", resp.Request.Method, resp.Request.URL, got)
+	}
+	return resp
+}
+
+// ExpectError checks that the body is an error with the message
+func (resp *Response) ExpectError(message string) *Response {
+	resp.tt.t.Helper()
+	var result struct {
+		Error interface{} `json:"error"`
+	}
+	resp.JSON(&result)
+	if result.Error != message {
+		resp.tt.t.Errorf("%s %s: error %v, want %q", resp.Request.Method, resp.Request.URL, result.Error, message)
+	}
+	return resp
+}
+
+// ExpectFieldError checks that the body is an error for the field (and with the message, if given)
+func (resp *Response) ExpectFieldError(name string, message ...string) *Response {
+	resp.tt.t.Helper()
+	var result struct {
+		Error *router.FieldError `json:"error"`
+	}
+	if json.Unmarshal(resp.Body(), &result) != nil || result.Error == nil {
+		resp.tt.t.Errorf("%s %s: not a field-error %q", resp.Request.Method, resp.Request.URL, resp.Text())
+		return resp
+	}
+	if result.Error.Name != name {
+		resp.tt.t.Errorf("%s %s: field-error on %q, want %q", resp.Request.Method, resp.Request.URL, result.Error.Name, name)
+	}
+	if len(message) > 0 && result.Error.Message != message[0] {
+		resp.tt.t.Errorf("%s %s: field-error %q, want %q", resp.Request.Method, resp.Request.URL, result.Error.Message, message[0])
+	}
+	return resp
+}
+
+// Logs returns the captured log-entries of the request, by its request-id
+func (resp *Response) Logs() []LogEntry {
+	id := resp.RequestID()
+	var entries []LogEntry
+	for _, entry := range resp.tt.Logs() {
+		if id != "" && entry.RequestID() == id {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// syncBuffer is a buffer safe for concurrent writes
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+	return sb.buf.String()
+}
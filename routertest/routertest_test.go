@@ -0,0 +1,100 @@
+package routertest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ninlil/butler/log"
+	"github.com/ninlil/butler/router"
+)
+
+type item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type itemArgs struct {
+	ID     int  `json:"id" from:"path" min:"1"`
+	Expand bool `json:"expand" from:"query"`
+}
+
+func getItem(r *http.Request, args *itemArgs) item {
+	log.FromCtx(r.Context()).Info().Msg("getting item")
+	name := "item"
+	if args.Expand {
+		name = "expanded item"
+	}
+	return item{ID: args.ID, Name: name}
+}
+
+type createArgs struct {
+	Body *item `from:"body"`
+}
+
+func createItem(args *createArgs) (*item, int) {
+	return args.Body, http.StatusCreated
+}
+
+var routes = []router.Route{
+	{Name: "get", Method: "GET", Path: "/items/{id}", Handler: getItem},
+	{Name: "create", Method: "POST", Path: "/items", Handler: createItem},
+}
+
+func TestTester(t *testing.T) {
+	rt := New(t, routes)
+
+	var got item
+	resp := rt.GET("/items/{id}", 42).WithQuery("expand", true).Expect(http.StatusOK).JSON(&got).ExpectRequestID()
+	if got.ID != 42 || got.Name != "expanded item" {
+		t.Errorf("got %+v", got)
+	}
+	if logs := resp.Logs(); len(logs) == 0 || logs[0].Message() != "getting item" {
+		t.Errorf("logs = %v", logs)
+	}
+
+	rt.GET("/items/{id}", 0).Expect(http.StatusBadRequest).ExpectFieldError("id")
+	rt.GET("/items/{id}", 1).WithHeader("X-Request-Id", "my-id").Expect(http.StatusOK).ExpectRequestID("my-id")
+	rt.GET("/missing").Expect(http.StatusNotFound).ExpectError("not found")
+
+	rt.POST("/items").WithBody(item{ID: 7, Name: "new"}).Expect(http.StatusCreated).JSON(&got)
+	if got.ID != 7 || got.Name != "new" {
+		t.Errorf("created %+v", got)
+	}
+
+	if rt.Router() == nil || len(rt.Logs()) == 0 {
+		t.Error("expected the router and its logs")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	rt := Wrap(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		_, _ = w.Write([]byte(r.URL.RequestURI()))
+	}))
+	resp := rt.DELETE("/a/{b}", "x y").WithQuery("q", 1).Expect(http.StatusOK).ExpectHeader("X-Method", "DELETE")
+	if resp.Text() != "/a/x%20y?q=1" {
+		t.Errorf("body = %q", resp.Text())
+	}
+	if rt.Router() != nil {
+		t.Error("Router() should be nil for other handlers")
+	}
+}
+
+func TestExpectFailures(t *testing.T) {
+	rt := New(t, routes)
+	ft := &fakeT{TB: t}
+	rt.t = ft
+
+	rt.GET("/items/{id}", 1).Expect(http.StatusTeapot).ExpectHeader("X-None", "1").ExpectFieldError("id")
+	if ft.errors != 3 {
+		t.Errorf("got %d errors, want 3", ft.errors)
+	}
+}
+
+// fakeT counts the errors instead of failing the test
+type fakeT struct {
+	testing.TB
+	errors int
+}
+
+func (ft *fakeT) Errorf(string, ...interface{}) { ft.errors++ }
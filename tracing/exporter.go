@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// MemoryExporter keeps all exported spans in memory, intended for tests
+type MemoryExporter struct {
+	mutex sync.Mutex
+	spans []SpanData
+}
+
+// NewMemoryExporter creates an empty MemoryExporter
+func NewMemoryExporter() *MemoryExporter {
+	return new(MemoryExporter)
+}
+
+// Export stores the spans
+func (me *MemoryExporter) Export(_ context.Context, spans []SpanData) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.spans = append(me.spans, spans...)
+	return nil
+}
+
+// Shutdown does nothing, the spans are kept
+func (me *MemoryExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// Spans returns a copy of all exported spans, use Flush to make sure all ended spans are included
+func (me *MemoryExporter) Spans() []SpanData {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return append([]SpanData(nil), me.spans...)
+}
+
+// Find returns the first exported span with the name, or nil if not found
+func (me *MemoryExporter) Find(name string) *SpanData {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for i := range me.spans {
+		if me.spans[i].Name == name {
+			sd := me.spans[i]
+			return &sd
+		}
+	}
+	return nil
+}
+
+// Reset removes all stored spans
+func (me *MemoryExporter) Reset() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.spans = nil
+}
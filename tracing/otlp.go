@@ -0,0 +1,211 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const scopeName = "github.com/ninlil/butler"
+
+// OTLPExporter exports spans using the OpenTelemetry protocol as JSON over HTTP
+type OTLPExporter struct {
+	endpoint string
+	service  string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// OTLPOption is for 'functional options' to NewOTLPExporter
+type OTLPOption func(*OTLPExporter)
+
+// WithServiceName sets the 'service.name' resource-attribute (default is the name of the executable)
+func WithServiceName(name string) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.service = name
+	}
+}
+
+// WithHeader adds a header to each export-request (ex: for authentication)
+func WithHeader(key, value string) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.headers[key] = value
+	}
+}
+
+// WithHTTPClient sets the http.Client used to send the spans
+func WithHTTPClient(client *http.Client) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.client = client
+	}
+}
+
+// NewOTLPExporter creates an exporter sending spans to the url of an OTLP/HTTP collector
+// (ex: "http://localhost:4318/v1/traces")
+func NewOTLPExporter(url string, opts ...OTLPOption) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint: url,
+		headers:  make(map[string]string),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	if exe, err := os.Executable(); err == nil {
+		e.service = filepath.Base(exe)
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Export sends the spans to the collector
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	buf, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("otlp: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Shutdown does nothing, all spans are sent by Export
+func (e *OTLPExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// The OTLP/JSON data model, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+func (e *OTLPExporter) encode(spans []SpanData) *otlpRequest {
+	list := make([]otlpSpan, 0, len(spans))
+	for i := range spans {
+		sd := &spans[i]
+		list = append(list, otlpSpan{
+			TraceID:           sd.TraceID,
+			SpanID:            sd.SpanID,
+			ParentSpanID:      sd.ParentID,
+			Name:              sd.Name,
+			Kind:              sd.Kind,
+			StartTimeUnixNano: strconv.FormatInt(sd.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(sd.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(sd.Attributes),
+			Status:            otlpStatus{Code: sd.Status, Message: sd.StatusMessage},
+		})
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes([]Attribute{{Key: "service.name", Value: e.service}}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: scopeName},
+				Spans: list,
+			}},
+		}},
+	}
+}
+
+func otlpAttributes(attrs []Attribute) []otlpKeyValue {
+	list := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var v otlpValue
+		switch value := attr.Value.(type) {
+		case string:
+			v.StringValue = &value
+		case bool:
+			v.BoolValue = &value
+		case int:
+			s := strconv.Itoa(value)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(value, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &value
+		case fmt.Stringer:
+			s := value.String()
+			v.StringValue = &s
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		list = append(list, otlpKeyValue{Key: attr.Key, Value: v})
+	}
+	return list
+}
@@ -0,0 +1,188 @@
+// Package tracing creates OpenTelemetry-compatible spans for requests and workers and
+// exports them using a pluggable Exporter
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/ninlil/butler/clock"
+)
+
+// SpanKind describes the relationship between the span and its parent/children
+type SpanKind int
+
+// SpanKind values, matching the OpenTelemetry protocol
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// StatusCode of a span, matching the OpenTelemetry protocol
+type StatusCode int
+
+// StatusCode values
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute is a key-value pair describing a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanData is a finished span, as handed to the Exporter
+type SpanData struct {
+	TraceID       string
+	SpanID        string
+	ParentID      string
+	Name          string
+	Kind          SpanKind
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+}
+
+// Attr returns the value of the attribute with the key, or nil if not found
+func (sd *SpanData) Attr(key string) interface{} {
+	for _, attr := range sd.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return nil
+}
+
+// Span is an ongoing span, a nil Span is valid and does nothing
+type Span struct {
+	mutex   sync.Mutex
+	data    SpanData
+	ended   bool
+	sampled bool
+	tracer  *tracer
+}
+
+type spanKey struct{}
+
+// FromCtx returns the current span of the context, or nil if none
+func FromCtx(ctx context.Context) *Span {
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return span
+	}
+	return nil
+}
+
+// TraceID returns the trace-id of the span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.data.TraceID
+}
+
+// SpanID returns the span-id of the span
+func (s *Span) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return s.data.SpanID
+}
+
+// Sampled returns true if the span will be exported
+func (s *Span) Sampled() bool {
+	if s == nil {
+		return false
+	}
+	return s.sampled
+}
+
+// SetName changes the name of the span
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data.Name = name
+}
+
+// SetAttr adds (or replaces) an attribute on the span
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.data.Attributes {
+		if s.data.Attributes[i].Key == key {
+			s.data.Attributes[i].Value = value
+			return
+		}
+	}
+	s.data.Attributes = append(s.data.Attributes, Attribute{Key: key, Value: value})
+}
+
+// SetStatus sets the status of the span
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data.Status = code
+	s.data.StatusMessage = msg
+}
+
+// RecordError sets the status of the span to StatusError with the message of the error
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End finishes the span and queues it for export, only the first call has any effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = clock.Now()
+	data := s.data
+	data.Attributes = append([]Attribute(nil), s.data.Attributes...)
+	s.mutex.Unlock()
+
+	if s.sampled {
+		s.tracer.queue(data)
+	}
+}
+
+// NewTraceID returns a new random trace-id (32 lowercase hex characters)
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID returns a new random span-id (16 lowercase hex characters)
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	clock.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,263 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ninlil/butler/clock"
+	"github.com/ninlil/butler/log"
+	"github.com/ninlil/butler/runtime"
+)
+
+type tracer struct {
+	exporter  Exporter
+	batchSize int
+	interval  time.Duration
+	timeout   time.Duration
+
+	spans   chan SpanData
+	flush   chan chan struct{}
+	done    chan struct{}
+	stopped sync.WaitGroup
+	dropped atomic.Int64
+}
+
+var current atomic.Pointer[tracer]
+
+// Option is for 'functional options' to Enable
+type Option func(*tracer)
+
+// WithBatchSize sets the max number of spans exported at once (default 512)
+func WithBatchSize(n int) Option {
+	return func(t *tracer) {
+		if n > 0 {
+			t.batchSize = n
+		}
+	}
+}
+
+// WithInterval sets how often queued spans are exported (default 5s)
+func WithInterval(d time.Duration) Option {
+	return func(t *tracer) {
+		if d > 0 {
+			t.interval = d
+		}
+	}
+}
+
+// WithQueueSize sets the max number of spans waiting for export, spans are dropped when full (default 2048)
+func WithQueueSize(n int) Option {
+	return func(t *tracer) {
+		if n > 0 {
+			t.spans = make(chan SpanData, n)
+		}
+	}
+}
+
+// WithExportTimeout sets the timeout of each call to the exporter (default 10s)
+func WithExportTimeout(d time.Duration) Option {
+	return func(t *tracer) {
+		if d > 0 {
+			t.timeout = d
+		}
+	}
+}
+
+// Enable starts the tracing, exporting the spans to the exporter.
+// Any previously enabled exporter is flushed and shut down.
+func Enable(exporter Exporter, opts ...Option) {
+	t := &tracer{
+		exporter:  exporter,
+		batchSize: 512,
+		interval:  5 * time.Second,
+		timeout:   10 * time.Second,
+		flush:     make(chan chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.spans == nil {
+		t.spans = make(chan SpanData, 2048)
+	}
+
+	t.stopped.Add(1)
+	go t.run()
+
+	if prev := current.Swap(t); prev != nil {
+		prev.stop()
+	}
+	runtime.OnClose("tracing", Disable)
+}
+
+// Disable stops the tracing, exporting any queued spans before returning
+func Disable() {
+	if t := current.Swap(nil); t != nil {
+		t.stop()
+	}
+}
+
+// Enabled returns true if tracing is enabled
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// Flush exports all queued spans before returning
+func Flush() {
+	if t := current.Load(); t != nil {
+		ch := make(chan struct{})
+		select {
+		case t.flush <- ch:
+			<-ch
+		case <-t.done:
+		}
+	}
+}
+
+// SpanOption is for 'functional options' to Start
+type SpanOption func(*Span)
+
+// WithKind sets the kind of the span (default KindInternal)
+func WithKind(kind SpanKind) SpanOption {
+	return func(s *Span) {
+		s.data.Kind = kind
+	}
+}
+
+// WithAttr adds an attribute to the span
+func WithAttr(key string, value interface{}) SpanOption {
+	return func(s *Span) {
+		s.data.Attributes = append(s.data.Attributes, Attribute{Key: key, Value: value})
+	}
+}
+
+// WithRemoteParent continues a trace from another process, instead of the span in the context (if any)
+func WithRemoteParent(traceID, parentID string, sampled bool) SpanOption {
+	return func(s *Span) {
+		s.data.TraceID = traceID
+		s.data.ParentID = parentID
+		s.sampled = sampled
+	}
+}
+
+// WithSpanID uses an already generated span-id instead of a new one
+func WithSpanID(id string) SpanOption {
+	return func(s *Span) {
+		s.data.SpanID = id
+	}
+}
+
+// Start creates a new span, as a child of the span in the context (if any).
+// If tracing isn't enabled, the context is returned as-is along with a nil-span.
+func Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	t := current.Load()
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer:  t,
+		sampled: true,
+		data: SpanData{
+			Name:  name,
+			Kind:  KindInternal,
+			Start: clock.Now(),
+		},
+	}
+	if parent := FromCtx(ctx); parent != nil {
+		span.data.TraceID = parent.data.TraceID
+		span.data.ParentID = parent.data.SpanID
+		span.sampled = parent.sampled
+	}
+	for _, opt := range opts {
+		opt(span)
+	}
+	if span.data.TraceID == "" {
+		span.data.TraceID = NewTraceID()
+	}
+	if span.data.SpanID == "" {
+		span.data.SpanID = NewSpanID()
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *tracer) queue(data SpanData) {
+	select {
+	case t.spans <- data:
+	default:
+		if t.dropped.Add(1) == 1 {
+			log.Warn().Msg("tracing: queue is full, dropping spans")
+		}
+	}
+}
+
+func (t *tracer) stop() {
+	close(t.done)
+	t.stopped.Wait()
+}
+
+func (t *tracer) run() {
+	defer t.stopped.Done()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, t.batchSize)
+	drain := func() {
+		for {
+			select {
+			case data := <-t.spans:
+				batch = append(batch, data)
+				if len(batch) >= t.batchSize {
+					batch = t.export(batch)
+				}
+			default:
+				batch = t.export(batch)
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case data := <-t.spans:
+			batch = append(batch, data)
+			if len(batch) >= t.batchSize {
+				batch = t.export(batch)
+			}
+		case <-ticker.C:
+			batch = t.export(batch)
+		case ch := <-t.flush:
+			drain()
+			close(ch)
+		case <-t.done:
+			drain()
+			ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+			if err := t.exporter.Shutdown(ctx); err != nil {
+				log.Error().Msgf("tracing: exporter shutdown-error: %v", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// export sends the batch to the exporter and returns an empty batch for reuse
+func (t *tracer) export(batch []SpanData) []SpanData {
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	if err := t.exporter.Export(ctx, batch); err != nil {
+		log.Error().Msgf("tracing: export of %d spans failed: %v", len(batch), err)
+	}
+	if n := t.dropped.Swap(0); n > 0 {
+		log.Warn().Msgf("tracing: %d spans were dropped", n)
+	}
+	return make([]SpanData, 0, t.batchSize)
+}
@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func enableMemory(t *testing.T) *MemoryExporter {
+	t.Helper()
+	exp := NewMemoryExporter()
+	Enable(exp)
+	t.Cleanup(Disable)
+	return exp
+}
+
+func TestStartDisabled(t *testing.T) {
+	ctx := context.Background()
+	ctx2, span := Start(ctx, "noop")
+	if span != nil || ctx2 != ctx {
+		t.Error("Start should return a nil-span and the same context when disabled")
+	}
+	// a nil-span must be safe to use
+	span.SetAttr("key", "value")
+	span.RecordError(errors.New("ignored"))
+	span.End()
+}
+
+func TestStartChild(t *testing.T) {
+	exp := enableMemory(t)
+
+	ctx, parent := Start(context.Background(), "parent", WithKind(KindServer), WithAttr("a", 1))
+	_, child := Start(ctx, "child")
+	child.RecordError(errors.New("failed"))
+	child.End()
+	parent.End()
+	parent.End() // only the first End counts
+	Flush()
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+	p, c := exp.Find("parent"), exp.Find("child")
+	if p == nil || c == nil {
+		t.Fatalf("missing spans: %+v", spans)
+	}
+	if c.TraceID != p.TraceID || c.ParentID != p.SpanID {
+		t.Errorf("child %s/%s is not a child of %s/%s", c.TraceID, c.ParentID, p.TraceID, p.SpanID)
+	}
+	if p.Kind != KindServer || c.Kind != KindInternal {
+		t.Errorf("kinds = %d/%d, want %d/%d", p.Kind, c.Kind, KindServer, KindInternal)
+	}
+	if c.Status != StatusError || c.StatusMessage != "failed" {
+		t.Errorf("child status = %d %q, want error", c.Status, c.StatusMessage)
+	}
+	if p.Attr("a") != 1 {
+		t.Errorf("attribute a = %v, want 1", p.Attr("a"))
+	}
+	if p.End.Before(p.Start) {
+		t.Error("span ended before it started")
+	}
+}
+
+func TestRemoteParent(t *testing.T) {
+	exp := enableMemory(t)
+
+	_, span := Start(context.Background(), "sampled",
+		WithRemoteParent("4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true),
+		WithSpanID("1111111111111111"))
+	span.End()
+	ctx, unsampled := Start(context.Background(), "unsampled",
+		WithRemoteParent("4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", false))
+	_, child := Start(ctx, "unsampled-child")
+	child.End()
+	unsampled.End()
+	Flush()
+
+	sd := exp.Find("sampled")
+	if sd == nil {
+		t.Fatal("sampled span was not exported")
+	}
+	if sd.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || sd.ParentID != "00f067aa0ba902b7" || sd.SpanID != "1111111111111111" {
+		t.Errorf("span ids = %s/%s/%s", sd.TraceID, sd.ParentID, sd.SpanID)
+	}
+	if exp.Find("unsampled") != nil || exp.Find("unsampled-child") != nil {
+		t.Error("unsampled spans should not be exported")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var got otlpRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		buf, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(buf, &got)
+	}))
+	defer srv.Close()
+
+	Enable(NewOTLPExporter(srv.URL, WithServiceName("test"), WithHeader("Authorization", "secret")))
+	t.Cleanup(Disable)
+
+	_, span := Start(context.Background(), "GET /items", WithKind(KindServer),
+		WithAttr("http.response.status_code", 200), WithAttr("ok", true))
+	span.End()
+	Flush()
+
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if name := *got.ResourceSpans[0].Resource.Attributes[0].Value.StringValue; name != "test" {
+		t.Errorf("service.name = %q, want %q", name, "test")
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 || spans[0].Name != "GET /items" || spans[0].Kind != KindServer {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+	if len(spans[0].TraceID) != 32 || len(spans[0].SpanID) != 16 {
+		t.Errorf("ids = %q/%q", spans[0].TraceID, spans[0].SpanID)
+	}
+	if v := spans[0].Attributes[0].Value.IntValue; v == nil || *v != "200" {
+		t.Errorf("status attribute = %v, want \"200\"", v)
+	}
+}
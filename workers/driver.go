@@ -1,10 +1,13 @@
 package workers
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/ninlil/butler/clock"
 	"github.com/ninlil/butler/log"
+	"github.com/ninlil/butler/tracing"
 	"github.com/rs/zerolog"
 )
 
@@ -68,25 +71,29 @@ func (d *driverType) start(done chan DoneBehavior) {
 
 func (d *driverType) startWorker(w *Worker) {
 	log := log.FromCtx(w.ctx)
+	ctx, span := tracing.Start(w.ctx, "worker "+w.name, tracing.WithAttr("worker.name", w.name))
 
 	defer func() {
 		w.state = stateDone
-		w.ended = time.Now()
+		w.ended = clock.Now()
 		if w.realPanic {
 			log.Debug().Msgf("workers: [%s] exit", w.name)
+			span.SetStatus(tracing.StatusError, "exit")
 		} else {
 			if err := recover(); err != nil {
 				w.state = statePanic
 				log.WithLevel(zerolog.PanicLevel).Caller(2).Msgf("worker-panic: %v", err)
+				span.SetStatus(tracing.StatusError, fmt.Sprint(err))
 			} else {
 				log.Debug().Msgf("workers: [%s] done", w.name)
 			}
 		}
+		span.End()
 		d.wg.Done()
 	}()
 
-	w.started = time.Now()
+	w.started = clock.Now()
 	w.state = stateRunning
 	log.Debug().Msgf("workers: [%s] starting...", w.name)
-	w.handler(w.ctx)
+	w.handler(ctx)
 }
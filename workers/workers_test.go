@@ -3,6 +3,8 @@ package workers
 import (
 	"context"
 	"testing"
+
+	"github.com/ninlil/butler/tracing"
 )
 
 func resetDriver() {
@@ -80,3 +82,34 @@ func TestNilWorker_IsActive(t *testing.T) {
 		t.Error("(*Worker)(nil).IsActive() = true, want false")
 	}
 }
+
+func TestWorkerSpan(t *testing.T) {
+	resetDriver()
+	t.Cleanup(resetDriver)
+
+	exp := tracing.NewMemoryExporter()
+	tracing.Enable(exp)
+	t.Cleanup(tracing.Disable)
+
+	var spanID string
+	New("span-gamma", func(ctx context.Context) {
+		spanID = tracing.FromCtx(ctx).SpanID()
+		panic("oops")
+	})
+
+	w := driver.list["span-gamma"]
+	driver.wg.Add(1)
+	driver.startWorker(w)
+	tracing.Flush()
+
+	sd := exp.Find("worker span-gamma")
+	if sd == nil {
+		t.Fatal("no span for the worker")
+	}
+	if sd.SpanID != spanID {
+		t.Errorf("span-id = %q, want the span in the worker context %q", sd.SpanID, spanID)
+	}
+	if sd.Status != tracing.StatusError {
+		t.Errorf("status = %d, want %d after panic", sd.Status, tracing.StatusError)
+	}
+}